@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// NotifiedListing is a recently notified listing, remembered to detect the listings which are ended and listed again
+// under a new item ID.
+type NotifiedListing struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+	Seller string `json:"seller,omitempty"`
+	Price  string `json:"price"`
+	// ImageHash is the perceptual hash of the thumbnail, 0 when it is unknown.
+	ImageHash  uint64    `json:"image_hash,omitempty"`
+	NotifiedAt time.Time `json:"notified_at"`
+}
+
+// LoadNotified loads the recently notified listings, from the json file.
+func LoadNotified() ([]NotifiedListing, error) {
+	f, err := os.OpenFile("notified.json", os.O_RDWR|os.O_CREATE, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("could not open notified.json: %v", err)
+	}
+	defer f.Close()
+
+	var res []NotifiedListing
+	err = json.NewDecoder(f).Decode(&res)
+	if err != nil {
+		log.Println("cannot decode notified listings, ignoring", err)
+	}
+
+	return res, nil
+}
+
+// UpdateNotified writes the given recently notified listings into the json file.
+func UpdateNotified(listings []NotifiedListing) error {
+	f, err := os.OpenFile("notified.json", os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("could not open notified.json: %v", err)
+	}
+	defer f.Close()
+
+	err = json.NewEncoder(f).Encode(listings)
+	if err != nil {
+		return fmt.Errorf("could not encode to notified.json: %v", err)
+	}
+
+	return nil
+}
@@ -8,9 +8,55 @@ import (
 	"time"
 )
 
+// MaxSeenListings is the number of recently seen listings kept in the cache for each search URL.
+const MaxSeenListings = 500
+
 type CachedListing struct {
 	URL  string    `json:"url"`
 	Date time.Time `json:"date"`
+	// Seen are the most recently seen listings of the search URL, newest first.
+	Seen []SeenListing `json:"seen,omitempty"`
+	// Alerted are the auctions notified by an ending soonest search URL, until they end.
+	Alerted []AlertedAuction `json:"alerted,omitempty"`
+}
+
+type SeenListing struct {
+	ID     string    `json:"id"`
+	SeenAt time.Time `json:"seen_at"`
+}
+
+// HasSeen returns whether the listing with the given item ID is among the recently seen listings.
+func (c CachedListing) HasSeen(ID string) bool {
+	for _, s := range c.Seen {
+		if s.ID == ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddSeen returns a copy of the CachedListing, with the given item IDs added as the most recently seen listings.
+// Only the MaxSeenListings most recent ones are kept.
+func (c CachedListing) AddSeen(IDs []string, at time.Time) CachedListing {
+	seen := make([]SeenListing, 0, len(IDs)+len(c.Seen))
+	for _, ID := range IDs {
+		seen = append(seen, SeenListing{ID: ID, SeenAt: at})
+	}
+
+	for _, s := range c.Seen {
+		if len(seen) >= MaxSeenListings {
+			break
+		}
+		seen = append(seen, s)
+	}
+
+	if len(seen) > MaxSeenListings {
+		seen = seen[:MaxSeenListings]
+	}
+
+	c.Seen = seen
+	return c
 }
 
 // LoadCache loads the cache, from the json file.
@@ -58,3 +104,38 @@ func readCache() (map[string]CachedListing, error) {
 
 	return res, nil
 }
+
+// AlertedAuction is an auction notified by an ending soonest search.
+type AlertedAuction struct {
+	ID     string    `json:"id"`
+	EndsAt time.Time `json:"ends_at"`
+}
+
+// HasAlerted returns whether the auction with the given item ID was already notified.
+func (c CachedListing) HasAlerted(ID string) bool {
+	for _, a := range c.Alerted {
+		if a.ID == ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddAlerted returns a copy of the CachedListing, with the given auctions added to the notified ones. The auctions
+// which ended before the given time are forgotten.
+func (c CachedListing) AddAlerted(auctions []AlertedAuction, at time.Time) CachedListing {
+	all := make([]AlertedAuction, 0, len(c.Alerted)+len(auctions))
+	all = append(all, c.Alerted...)
+	all = append(all, auctions...)
+
+	var alerted []AlertedAuction
+	for _, a := range all {
+		if !a.EndsAt.Before(at) {
+			alerted = append(alerted, a)
+		}
+	}
+
+	c.Alerted = alerted
+	return c
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAddSeen(t *testing.T) {
+	now := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	c := CachedListing{URL: "https://www.ebay.com/itm/1"}
+
+	c = c.AddSeen([]string{"3", "2"}, now)
+	c = c.AddSeen([]string{"5", "4"}, now.Add(time.Minute))
+
+	if len(c.Seen) != 4 || c.Seen[0].ID != "5" || c.Seen[3].ID != "2" {
+		t.Errorf("expected the seen listings to be sorted from the newest, got %+v", c.Seen)
+	}
+
+	if !c.HasSeen("3") || c.HasSeen("1") {
+		t.Errorf("unexpected seen listings %+v", c.Seen)
+	}
+
+	t.Run("Bounded window", func(t *testing.T) {
+		IDs := make([]string, MaxSeenListings)
+		for i := range IDs {
+			IDs[i] = fmt.Sprintf("new-%d", i)
+		}
+
+		c := c.AddSeen(IDs, now.Add(time.Hour))
+		if len(c.Seen) != MaxSeenListings {
+			t.Errorf("expected %d seen listings but got %d", MaxSeenListings, len(c.Seen))
+		}
+
+		if c.HasSeen("2") || !c.HasSeen("new-0") {
+			t.Errorf("expected the oldest seen listings to be dropped")
+		}
+	})
+}
+
+func TestAddAlerted(t *testing.T) {
+	now := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	c := CachedListing{Alerted: []AlertedAuction{
+		{ID: "1", EndsAt: now.Add(-time.Minute)},
+		{ID: "2", EndsAt: now.Add(time.Minute)},
+	}}
+
+	c = c.AddAlerted([]AlertedAuction{{ID: "3", EndsAt: now.Add(time.Hour)}}, now)
+
+	if c.HasAlerted("1") || !c.HasAlerted("2") || !c.HasAlerted("3") {
+		t.Errorf("expected the ended auctions to be forgotten, got %+v", c.Alerted)
+	}
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// SoldListing is the price of a recently sold listing.
+type SoldListing struct {
+	ID       string    `json:"id"`
+	Amount   float64   `json:"amount"`
+	Currency string    `json:"currency"`
+	SeenAt   time.Time `json:"seen_at"`
+}
+
+// LoadSold loads the recently sold listings of each search URL, from the json file.
+func LoadSold() (map[string][]SoldListing, error) {
+	f, err := os.OpenFile("sold.json", os.O_RDWR|os.O_CREATE, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("could not open sold.json: %v", err)
+	}
+	defer f.Close()
+
+	res := map[string][]SoldListing{}
+	err = json.NewDecoder(f).Decode(&res)
+	if err != nil {
+		log.Println("cannot decode sold listings, ignoring", err)
+	}
+
+	return res, nil
+}
+
+// UpdateSold writes the given recently sold listings of each search URL into the json file.
+func UpdateSold(sold map[string][]SoldListing) error {
+	f, err := os.OpenFile("sold.json", os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("could not open sold.json: %v", err)
+	}
+	defer f.Close()
+
+	err = json.NewEncoder(f).Encode(sold)
+	if err != nil {
+		return fmt.Errorf("could not encode to sold.json: %v", err)
+	}
+
+	return nil
+}
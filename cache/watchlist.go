@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// watchlistFile is shared by the watchdog and the command line, which can both edit the watchlist.
+const watchlistFile = "watchlist.json"
+
+// WatchedItem is an item of the watchlist, with its state when it was last checked.
+type WatchedItem struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+
+	Title             string    `json:"title,omitempty"`
+	Price             string    `json:"price,omitempty"`
+	Bids              int       `json:"bids"`
+	Available         int       `json:"available"`
+	BestOfferAccepted bool      `json:"best_offer_accepted"`
+	CheckedAt         time.Time `json:"checked_at"`
+}
+
+// LoadWatchlist loads the watched items, from the json file. A file which cannot be decoded is an error, so that the
+// watchlist is not overwritten with the items of a wrongly read one.
+func LoadWatchlist() ([]WatchedItem, error) {
+	return loadWatchlist(watchlistFile)
+}
+
+func loadWatchlist(path string) ([]WatchedItem, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var res []WatchedItem
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %v", path, err)
+	}
+
+	return res, nil
+}
+
+// UpdateWatchlist writes the given watched items into the json file. The file is replaced at once, so that it is
+// never read partially written by the other process.
+func UpdateWatchlist(items []WatchedItem) error {
+	return updateWatchlist(watchlistFile, items)
+}
+
+func updateWatchlist(path string, items []WatchedItem) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not create a temporary file for %s: %v", path, err)
+	}
+	defer os.Remove(f.Name())
+
+	err = json.NewEncoder(f).Encode(items)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not encode to %s: %v", path, err)
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return fmt.Errorf("could not replace %s: %v", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestWatchlistFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchlist.json")
+
+	items, err := loadWatchlist(path)
+	if err != nil || len(items) != 0 {
+		t.Fatalf("expected an empty watchlist without file, got %v, %v", items, err)
+	}
+
+	err = updateWatchlist(path, []WatchedItem{{ID: "1"}, {ID: "2"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items, err = loadWatchlist(path)
+	if err != nil || len(items) != 2 || items[1].ID != "2" {
+		t.Errorf("expected the 2 saved items, got %v, %v", items, err)
+	}
+
+	if leftovers, _ := filepath.Glob(path + ".*.tmp"); len(leftovers) > 0 {
+		t.Errorf("expected no temporary file left, got %v", leftovers)
+	}
+
+	t.Run("Invalid file", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte(`[{"id": "1"`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := loadWatchlist(path); err == nil {
+			t.Errorf("expected an error for a watchlist which cannot be decoded")
+		}
+	})
+}
+
+func TestWatchlistConcurrentAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchlist.json")
+
+	items := make([]WatchedItem, 200)
+	for i := range items {
+		items[i] = WatchedItem{ID: fmt.Sprintf("%d", i), URL: fmt.Sprintf("https://www.ebay.com/itm/%d", i)}
+	}
+	if err := updateWatchlist(path, items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := updateWatchlist(path, items); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		read, err := loadWatchlist(path)
+		if err != nil || len(read) != len(items) {
+			t.Fatalf("read %d items (%v) while the watchlist was written, expected %d", len(read), err, len(items))
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"ebay-watchdog/coordinator"
+	"ebay-watchdog/web"
+	"fmt"
+	"time"
+)
+
+const usage = `usage:
+  ebay-watchdog                        start the watchdog
+  ebay-watchdog watch <item>...        add items, by ID or item page URL, to the watchlist
+  ebay-watchdog unwatch <item ID>...   remove items from the watchlist
+  ebay-watchdog watchlist              list the watched items`
+
+// runCommand runs the given command line command, which edits the watchlist.
+func runCommand(args []string) error {
+	w := coordinator.NewWatcher(time.Minute, web.DefaultClient)
+
+	switch args[0] {
+	case "watch":
+		if len(args) < 2 {
+			return fmt.Errorf("missing items to watch\n%s", usage)
+		}
+
+		added, err := w.Add(args[1:])
+		if err != nil {
+			return fmt.Errorf("could not add items to the watchlist: %v", err)
+		}
+		fmt.Printf("Added %d item(s) to the watchlist\n", len(added))
+	case "unwatch":
+		if len(args) < 2 {
+			return fmt.Errorf("missing items to unwatch\n%s", usage)
+		}
+
+		removed, err := w.Remove(args[1:])
+		if err != nil {
+			return fmt.Errorf("could not remove items from the watchlist: %v", err)
+		}
+		fmt.Printf("Removed %d item(s) from the watchlist\n", len(removed))
+	case "watchlist":
+		items, err := w.List()
+		if err != nil {
+			return fmt.Errorf("could not read the watchlist: %v", err)
+		}
+
+		for _, item := range items {
+			fmt.Printf("%s\t%s\t%s\t%s\n", item.ID, item.Price, item.Title, item.URL)
+		}
+	default:
+		return fmt.Errorf("unknown command %s\n%s", args[0], usage)
+	}
+
+	return nil
+}
@@ -9,14 +9,247 @@ import (
 )
 
 type Config struct {
-	Delay    int
-	Message  string
-	Searches []SearchItem
+	Delay int
+	// MaxPages is the default maximum number of results pages read in one scraping loop, for each search.
+	MaxPages    int `toml:"max_pages"`
+	Message     string
+	Searches    []SearchItem
+	Selectors   []SelectorProfile
+	Diagnostics Diagnostics
+	API         API
+	Enrichment  Enrichment
+	// Filter applies to the listings of all the searches.
+	Filter Filter
+	// Currency converts the prices, for the price filters and the notifications.
+	Currency Currency
+	// Sellers are the seller lists applying to all the searches.
+	Sellers Sellers
+	// Relist detects the listings which were ended and listed again under a new item ID.
+	Relist Relist
+	// Deals compares the prices of the listings to the recently sold listings of their search.
+	Deals Deals
+	// Watchlist checks the item pages of specific items periodically.
+	Watchlist Watchlist
+	// HTTP configures the HTTP client shared by the scraper and the notifications.
+	HTTP HTTP
 }
 
+// HTTP configures the connections and the timeouts of the HTTP client. The zero values use the defaults.
+type HTTP struct {
+	// PageTimeout, APITimeout and TelegramTimeout are the timeouts, in seconds, of the requests to the eBay pages,
+	// the eBay Browse API and Telegram. Default to 10.
+	PageTimeout     int `toml:"page_timeout"`
+	APITimeout      int `toml:"api_timeout"`
+	TelegramTimeout int `toml:"telegram_timeout"`
+	// MaxIdleConns is the maximum number of idle connections kept open, MaxIdleConnsPerHost the maximum for each
+	// host. Default to 100 and 10.
+	MaxIdleConns        int `toml:"max_idle_conns"`
+	MaxIdleConnsPerHost int `toml:"max_idle_conns_per_host"`
+	// IdleConnTimeout is the period, in seconds, after which an idle connection is closed. Defaults to 90.
+	IdleConnTimeout int `toml:"idle_conn_timeout"`
+	// DisableHTTP2 only uses HTTP/1.1.
+	DisableHTTP2 bool `toml:"disable_http2"`
+}
+
+// Watchlist configures the watched items, which are notified when their price, bids or quantity change, when a best
+// offer is accepted, and when they end.
+type Watchlist struct {
+	Enabled bool
+	// Interval is the period, in minutes, between two checks of the watched items. Defaults to 10.
+	Interval int
+	// Items are item IDs or item page URLs added to the watchlist at startup.
+	Items []string
+	// Buttons adds a Watch button to the notifications, which adds the listing to the watchlist.
+	Buttons bool
+}
+
+// Deals configures the price distribution of the recently sold listings of each search.
+type Deals struct {
+	Enabled bool
+	// Interval is the period, in minutes, between two scrapings of the sold listings. Defaults to 360.
+	Interval int
+	// Window is the period, in days, during which the sold listings are kept. Defaults to 30.
+	Window int
+}
+
+// Relist configures the detection of the relisted listings, compared to the recently notified ones.
+type Relist struct {
+	Enabled bool
+	// Action is suppress, to not notify the relisted listings, or annotate, to notify them with the relisted tag.
+	// Defaults to annotate.
+	Action string
+	// Window is the period, in hours, during which the notified listings are remembered. Defaults to 72.
+	Window int
+	// Similarity is the minimum similarity of the titles, between 0 and 1. Defaults to 0.8.
+	Similarity float64
+	// Images also compares the thumbnails, one more request per listing.
+	Images bool
+}
+
+// Sellers are seller usernames, case insensitive. When there are allowed sellers, only their listings are notified.
+// The listings of the denied sellers are never notified.
+type Sellers struct {
+	Allow []string
+	Deny  []string
+	// File is a toml file with allow and deny arrays, read again whenever it changes, so that the lists can be edited
+	// while the watchdog runs. Only used in the global lists.
+	File string
+}
+
+// Currency configures the conversion of the prices into a base currency, with exchange rates configured locally.
+type Currency struct {
+	// Base is the currency the prices are converted into, e.g. EUR. The conversion is disabled when not set.
+	Base string
+	// Rates are the values of one unit of each currency in the base currency, e.g. USD = 0.92.
+	Rates map[string]float64
+	// File is a toml file with rates in the same format, read again whenever it changes. Its rates take precedence.
+	File string
+}
+
+// SearchItem is a search to watch. It is either a raw eBay search URL, or structured fields from which the search URL
+// is built.
 type SearchItem struct {
 	URL     string
 	Domains []string
+	// Keywords are the words to search for.
+	Keywords string
+	// Exclude are the words the listings must not contain.
+	Exclude []string
+	// Seller restricts the search to the listings of the seller with this username. A seller search needs neither
+	// keywords nor category, and watches all the listings of the seller.
+	Seller string
+	// Category is the eBay category ID to search in. 0 means all the categories.
+	Category int
+	// MinPrice and MaxPrice bound the price of the listings, in the currency of each site. 0 means no bound.
+	MinPrice float64 `toml:"min_price"`
+	MaxPrice float64 `toml:"max_price"`
+	// Condition restricts the listings to the given conditions: new, open_box, certified_refurbished,
+	// seller_refurbished, used, for_parts.
+	Condition []string
+	// BuyingFormat restricts the listings to the given buying formats: auction, buy_it_now, best_offer.
+	BuyingFormat []string `toml:"buying_format"`
+	// Location restricts the item location: domestic, region or worldwide.
+	Location string
+	// Sold searches for the sold listings only.
+	Sold bool
+	// FreeShipping restricts the listings to the ones with free shipping.
+	FreeShipping bool `toml:"free_shipping"`
+	// Source is the way the listings are fetched: html (default) scrapes the search results pages, api uses the eBay
+	// Browse API, rss reads the RSS feed of the search.
+	Source string
+	// Mode is newest (default), to notify the newly listed items, or ending, to notify the auctions ending soon.
+	Mode string
+	// Ending configures the ending mode.
+	Ending Ending
+	// FlagFewerWords keeps scraping the "Results matching fewer words" section of the results, instead of stopping
+	// there. The listings from that section are flagged.
+	FlagFewerWords bool `toml:"flag_fewer_words"`
+	// NotifySponsored sends the new sponsored listings as well. They are skipped by default.
+	NotifySponsored bool `toml:"notify_sponsored"`
+	// MaxPages is the maximum number of results pages read in one scraping loop, when the last scraped listing is
+	// not found on the first page. Defaults to the global max_pages.
+	MaxPages int `toml:"max_pages"`
+	// Filter applies to the listings of this search, in addition to the global filter.
+	Filter Filter
+	// Rules decide, in order, what to do with the listings of this search which pass the filters.
+	Rules []Rule
+	// Sellers are the seller lists of this search, in addition to the global ones.
+	Sellers Sellers
+}
+
+// Ending configures the searches notifying the auctions which are about to end.
+type Ending struct {
+	// Window is the period, in minutes, before the end of an auction during which it is notified. Defaults to 60.
+	Window int
+	// MaxBids only notifies the auctions with fewer bids. 0 means no bound.
+	MaxBids int `toml:"max_bids"`
+	// MaxPrice only notifies the auctions with a lower current price, in the currency of the site. 0 means no bound.
+	MaxPrice float64 `toml:"max_price"`
+}
+
+// Rule applies its action to the listings matching its expression, e.g.
+// price < 200 EUR and (free_shipping or location contains 'Germany') and title !~ 'broken'.
+type Rule struct {
+	When string
+	// Action is drop, notify, silent (notify without sound) or tag.
+	Action string
+	// Tag is the tag added by the tag action.
+	Tag string
+}
+
+// Filter are the rules the listings must meet to be notified. The keywords, regular expressions and conditions are
+// case insensitive, and matched against the title and subtitle of the listings.
+type Filter struct {
+	// Include are keywords which must all appear.
+	Include []string
+	// Exclude are keywords which must not appear, e.g. "for parts", "box only".
+	Exclude []string
+	// Regex are regular expressions which must all match.
+	Regex []string
+	// ExcludeRegex are regular expressions which must not match.
+	ExcludeRegex []string `toml:"exclude_regex"`
+	// Condition are the accepted conditions of the listings, e.g. "new", "used". The listings without condition are
+	// kept.
+	Condition []string
+	// MinPrice and MaxPrice bound the price of the listings, in the base currency. 0 means no bound.
+	MinPrice float64 `toml:"min_price"`
+	MaxPrice float64 `toml:"max_price"`
+	// MaxPercentile only keeps the listings cheaper than this percentile of the recently sold listings, e.g. 30.
+	// Needs the deals to be enabled.
+	MaxPercentile float64 `toml:"max_percentile"`
+}
+
+// API configures the eBay Browse API, used by the searches with the api source. The credentials are read from the
+// EBAY_CLIENT_ID and EBAY_CLIENT_SECRET environment variables.
+type API struct {
+	// URL is the base URL of the eBay APIs, e.g. https://api.sandbox.ebay.com. Defaults to the production one.
+	URL string
+}
+
+// Enrichment configures the optional stage fetching the item page of each new listing, to add the seller, item
+// specifics, description and returns policy to the listings.
+type Enrichment struct {
+	Enabled bool
+	// Concurrency is the number of item pages fetched at the same time.
+	Concurrency int
+	// Delay is the minimum period, in milliseconds, between two item page requests.
+	Delay int
+	// Description also fetches the full description, which is one more request per listing.
+	Description bool
+}
+
+// Diagnostics configures what happens when a search results page looks like it could not be scraped correctly.
+type Diagnostics struct {
+	// Dir is the directory where the offending pages are saved.
+	Dir string
+	// AlertInterval is the minimum period, in seconds, between two alerts for the same domain and reason.
+	AlertInterval int `toml:"alert_interval"`
+}
+
+// SelectorProfile overrides the CSS selectors used to scrape the search results pages, for the given domains (or
+// all of them if empty). Each selector is a list of alternatives, tried in order. Missing selectors are taken from
+// the built-in profile.
+type SelectorProfile struct {
+	Version   string
+	Domains   []string
+	Container []string
+	Item      []string
+	Link      []string
+	Title     []string
+	Subtitle  []string
+	Price     []string
+	Date      []string
+	Image     []string
+	Condition []string
+	Shipping  []string
+	Location  []string
+	Seller    []string
+	TimeLeft  []string `toml:"time_left"`
+	Bids      []string
+	Count     []string
+	Boundary  []string
+	Sponsored []string
+	Next      []string
 }
 
 // Load loads the toml config, and the .env file. It returns the Config struct with the values from the toml file.
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// conditionIDs maps the item conditions of the structured searches to the eBay condition IDs.
+var conditionIDs = map[string]string{
+	"new":                   "1000",
+	"open_box":              "1500",
+	"certified_refurbished": "2000",
+	"seller_refurbished":    "2500",
+	"used":                  "3000",
+	"for_parts":             "7000",
+}
+
+// buyingFormatParams maps the buying formats of the structured searches to the eBay search parameters.
+var buyingFormatParams = map[string]string{
+	"auction":    "LH_Auction",
+	"buy_it_now": "LH_BIN",
+	"best_offer": "LH_BO",
+	"all":        "",
+}
+
+// locationParams maps the item locations of the structured searches to the eBay LH_PrefLoc values.
+var locationParams = map[string]string{
+	"domestic":  "1",
+	"worldwide": "2",
+	"region":    "3",
+	"":          "",
+}
+
+// modeEnding is the mode of the searches notifying the auctions which are about to end.
+const modeEnding = "ending"
+
+// isStructured returns whether the search is defined with structured fields rather than with a raw URL.
+func (s SearchItem) isStructured() bool {
+	return s.URL == ""
+}
+
+// Validate returns an error if the structured fields of the search cannot be turned into an eBay search URL. The
+// error does not identify the search, whose keywords may be empty: the caller tells which search it is.
+func (s SearchItem) Validate() error {
+	if !s.isStructured() {
+		return nil
+	}
+
+	if strings.TrimSpace(s.Keywords) == "" && s.Category == 0 && strings.TrimSpace(s.Seller) == "" {
+		return fmt.Errorf("missing url, keywords, category or seller")
+	}
+
+	if strings.ContainsAny(strings.TrimSpace(s.Seller), " /?&") {
+		return fmt.Errorf("invalid seller username %q", s.Seller)
+	}
+
+	if s.MinPrice < 0 || s.MaxPrice < 0 || (s.MaxPrice > 0 && s.MinPrice > s.MaxPrice) {
+		return fmt.Errorf("invalid price range %v-%v", s.MinPrice, s.MaxPrice)
+	}
+
+	for _, c := range s.Condition {
+		if _, ok := conditionIDs[c]; !ok {
+			return fmt.Errorf("unknown condition %s", c)
+		}
+	}
+
+	for _, f := range s.BuyingFormat {
+		if _, ok := buyingFormatParams[f]; !ok {
+			return fmt.Errorf("unknown buying format %s", f)
+		}
+	}
+
+	if _, ok := locationParams[s.Location]; !ok {
+		return fmt.Errorf("unknown location %s", s.Location)
+	}
+
+	if s.Mode == modeEnding && s.Sold {
+		return fmt.Errorf("cannot look for sold listings in the ending mode")
+	}
+
+	return nil
+}
+
+// SearchURL returns the eBay search URL of the search, on the given eBay host, e.g. www.ebay.com. The raw URL is
+// returned as is when set. The built URL lists the newly listed items first, or the auctions ending soonest in the
+// ending mode.
+func (s SearchItem) SearchURL(host string) string {
+	if !s.isStructured() {
+		return s.URL
+	}
+
+	keywords := strings.Fields(s.Keywords)
+	for _, w := range s.Exclude {
+		keywords = append(keywords, "-"+w)
+	}
+
+	params := url.Values{}
+	if len(keywords) > 0 {
+		params.Set("_nkw", strings.Join(keywords, " "))
+	}
+	params.Set("_sacat", strconv.Itoa(s.Category))
+
+	if seller := strings.TrimSpace(s.Seller); seller != "" {
+		params.Set("_ssn", seller)
+	}
+	params.Set("_sop", "10")
+	if s.Mode == modeEnding {
+		params.Set("_sop", "1")
+		params.Set("LH_Auction", "1")
+	}
+
+	if s.MinPrice > 0 {
+		params.Set("_udlo", formatPrice(s.MinPrice))
+	}
+	if s.MaxPrice > 0 {
+		params.Set("_udhi", formatPrice(s.MaxPrice))
+	}
+
+	if len(s.Condition) > 0 {
+		ids := make([]string, len(s.Condition))
+		for i, c := range s.Condition {
+			ids[i] = conditionIDs[c]
+		}
+		params.Set("LH_ItemCondition", strings.Join(ids, "|"))
+	}
+
+	for _, f := range s.BuyingFormat {
+		if p := buyingFormatParams[f]; p != "" {
+			params.Set(p, "1")
+		}
+	}
+
+	if loc := locationParams[s.Location]; loc != "" {
+		params.Set("LH_PrefLoc", loc)
+	}
+
+	if s.Sold {
+		params.Set("LH_Sold", "1")
+		params.Set("LH_Complete", "1")
+	}
+
+	if s.FreeShipping {
+		params.Set("LH_FS", "1")
+	}
+
+	u := url.URL{
+		Scheme:   "https",
+		Host:     host,
+		Path:     "/sch/i.html",
+		RawQuery: params.Encode(),
+	}
+
+	return u.String()
+}
+
+// formatPrice formats the given price without useless decimals, e.g. 20 or 19.99.
+func formatPrice(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64)
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSearchURL(t *testing.T) {
+	s := SearchItem{
+		Keywords:     "macbook pro",
+		Exclude:      []string{"broken", "parts"},
+		Category:     111422,
+		MinPrice:     200,
+		MaxPrice:     899.99,
+		Condition:    []string{"new", "used"},
+		BuyingFormat: []string{"buy_it_now", "best_offer"},
+		Location:     "domestic",
+		FreeShipping: true,
+	}
+
+	if err := s.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	u, err := url.Parse(s.SearchURL("www.ebay.co.uk"))
+	if err != nil {
+		t.Fatalf("could not parse the search URL: %v", err)
+	}
+
+	if u.Host != "www.ebay.co.uk" || u.Path != "/sch/i.html" {
+		t.Errorf("unexpected search URL %s", u)
+	}
+
+	exp := map[string]string{
+		"_nkw":             "macbook pro -broken -parts",
+		"_sacat":           "111422",
+		"_sop":             "10",
+		"_udlo":            "200",
+		"_udhi":            "899.99",
+		"LH_ItemCondition": "1000|3000",
+		"LH_BIN":           "1",
+		"LH_BO":            "1",
+		"LH_PrefLoc":       "1",
+		"LH_FS":            "1",
+	}
+
+	query := u.Query()
+	for k, v := range exp {
+		if got := query.Get(k); got != v {
+			t.Errorf("expected %s=%s but got %s", k, v, got)
+		}
+	}
+
+	for _, k := range []string{"LH_Auction", "LH_Sold", "LH_Complete"} {
+		if query.Get(k) != "" {
+			t.Errorf("expected no %s parameter", k)
+		}
+	}
+}
+
+func TestSearchURLRaw(t *testing.T) {
+	raw := "https://www.ebay.com/sch/i.html?_nkw=duct+tape&_sop=10"
+	s := SearchItem{URL: raw, Keywords: "ignored"}
+
+	if got := s.SearchURL("www.ebay.fr"); got != raw {
+		t.Errorf("expected %s but got %s", raw, got)
+	}
+}
+
+func TestSearchValidate(t *testing.T) {
+	invalid := map[string]SearchItem{
+		"empty":         {},
+		"price range":   {Keywords: "tape", MinPrice: 20, MaxPrice: 10},
+		"condition":     {Keywords: "tape", Condition: []string{"mint"}},
+		"buying format": {Keywords: "tape", BuyingFormat: []string{"swap"}},
+		"location":      {Keywords: "tape", Location: "moon"},
+		"seller":        {Seller: "tape seller"},
+	}
+
+	for name, s := range invalid {
+		if err := s.Validate(); err == nil {
+			t.Errorf("expected an error for an invalid %s", name)
+		}
+	}
+
+	if err := (SearchItem{Category: 625, Sold: true}).Validate(); err != nil {
+		t.Errorf("unexpected error for a category search: %v", err)
+	}
+
+	if err := (SearchItem{Seller: "tape_seller"}).Validate(); err != nil {
+		t.Errorf("unexpected error for a seller search: %v", err)
+	}
+
+	err := (SearchItem{Seller: "tape seller"}).Validate()
+	if err == nil || !strings.Contains(err.Error(), `"tape seller"`) {
+		t.Errorf("expected the error to quote the seller, got %v", err)
+	}
+}
+
+func TestSearchURLSeller(t *testing.T) {
+	s := SearchItem{Seller: " tape_seller "}
+
+	exp := "https://www.ebay.co.uk/sch/i.html?_sacat=0&_sop=10&_ssn=tape_seller"
+	if got := s.SearchURL("www.ebay.co.uk"); got != exp {
+		t.Errorf("expected %s but got %s", exp, got)
+	}
+}
+
+func TestSearchURLEnding(t *testing.T) {
+	s := SearchItem{Keywords: "tape", Mode: "ending"}
+
+	u, err := url.Parse(s.SearchURL("www.ebay.com"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if q := u.Query(); q.Get("_sop") != "1" || q.Get("LH_Auction") != "1" {
+		t.Errorf("expected the auctions ending soonest, got %s", u)
+	}
+}
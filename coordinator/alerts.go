@@ -0,0 +1,103 @@
+package coordinator
+
+import (
+	"ebay-watchdog/scraper"
+	"ebay-watchdog/web"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// layoutAlerter notifies the operator when the scraper reports a layout break. Alerts are rate limited per domain
+// and reason, and the offending page is saved in the diagnostics directory along with each alert. It can be used by
+// several goroutines.
+type layoutAlerter struct {
+	dir      string
+	interval time.Duration
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+	now      func() time.Time
+	send     func(msg string) error
+}
+
+func newLayoutAlerter(dir string, interval time.Duration, client *web.Client) *layoutAlerter {
+	if dir == "" {
+		dir = "diagnostics"
+	}
+
+	if interval <= 0 {
+		interval = 6 * time.Hour
+	}
+
+	return &layoutAlerter{
+		dir:      dir,
+		interval: interval,
+		lastSent: make(map[string]time.Time),
+		now:      time.Now,
+		send: func(msg string) error {
+			return sendOperatorMessage(client, msg)
+		},
+	}
+}
+
+// handle is meant to be used as the scraper.Scraper OnLayoutBreak hook.
+func (a *layoutAlerter) handle(b scraper.LayoutBreak) {
+	key := b.Domain + "|" + b.Reason
+	now := a.now()
+	a.mu.Lock()
+	if last, ok := a.lastSent[key]; ok && now.Sub(last) < a.interval {
+		a.mu.Unlock()
+		return
+	}
+	a.lastSent[key] = now
+	a.mu.Unlock()
+
+	path, err := a.save(b, now)
+	if err != nil {
+		log.Println("could not save the diagnostics page", err)
+		path = "not saved"
+	}
+
+	msg := fmt.Sprintf(
+		"ebay-watchdog: possible eBay layout change on domain %s\n%s\n%s\nPage saved to %s",
+		b.Domain, b.Reason, b.URL, path,
+	)
+	err = a.send(msg)
+	if err != nil {
+		log.Println("could not send the layout break alert", err)
+	}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// save writes the HTML of the given layout break into the diagnostics directory, and returns the file path.
+func (a *layoutAlerter) save(b scraper.LayoutBreak, now time.Time) (string, error) {
+	err := os.MkdirAll(a.dir, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("could not create diagnostics directory %s: %v", a.dir, err)
+	}
+
+	name := fmt.Sprintf("%s-%s.html", now.Format("20060102-150405"), unsafeFileChars.ReplaceAllString(b.Domain, "_"))
+	path := filepath.Join(a.dir, name)
+
+	err = os.WriteFile(path, []byte(b.HTML), 0644)
+	if err != nil {
+		return "", fmt.Errorf("could not write %s: %v", path, err)
+	}
+
+	return path, nil
+}
+
+// sendOperatorMessage sends the given message to the operator chat, which defaults to the notifications chat.
+func sendOperatorMessage(client *web.Client, msg string) error {
+	chatID := os.Getenv("TELEGRAM_ALERT_CHAT_ID")
+	if chatID == "" {
+		chatID = os.Getenv("TELEGRAM_CHAT_ID")
+	}
+
+	return client.SendTelegramMessage(os.Getenv("TELEGRAM_TOKEN"), chatID, msg, false)
+}
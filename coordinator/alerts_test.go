@@ -0,0 +1,105 @@
+package coordinator
+
+import (
+	"ebay-watchdog/scraper"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestAlerter returns a layoutAlerter saving the pages into a temporary directory, using the given clock, and
+// recording the alerts sent.
+func newTestAlerter(t *testing.T, now *time.Time, sent *[]string) *layoutAlerter {
+	a := newLayoutAlerter(t.TempDir(), time.Hour, nil)
+	a.now = func() time.Time { return *now }
+	var mu sync.Mutex
+	a.send = func(msg string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		*sent = append(*sent, msg)
+		return nil
+	}
+
+	return a
+}
+
+func TestLayoutAlerterRateLimit(t *testing.T) {
+	now := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	var sent []string
+	a := newTestAlerter(t, &now, &sent)
+
+	noItems := scraper.LayoutBreak{URL: "https://www.ebay.com/sch/i.html?_nkw=tape", Domain: "com", Reason: "no items"}
+	a.handle(noItems)
+	a.handle(noItems)
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(sent))
+	}
+
+	// The other domains and reasons have their own rate limit.
+	a.handle(scraper.LayoutBreak{Domain: "de", Reason: "no items"})
+	a.handle(scraper.LayoutBreak{Domain: "com", Reason: "empty titles"})
+	if len(sent) != 3 {
+		t.Fatalf("expected 3 alerts, got %d", len(sent))
+	}
+
+	now = now.Add(59 * time.Minute)
+	a.handle(noItems)
+	if len(sent) != 3 {
+		t.Errorf("expected no alert before the interval, got %d alerts", len(sent))
+	}
+
+	now = now.Add(time.Minute)
+	a.handle(noItems)
+	if len(sent) != 4 {
+		t.Errorf("expected a new alert after the interval, got %d alerts", len(sent))
+	}
+
+	if !strings.Contains(sent[0], "domain com") || !strings.Contains(sent[0], noItems.URL) {
+		t.Errorf("unexpected alert %q", sent[0])
+	}
+}
+
+func TestLayoutAlerterSavesPage(t *testing.T) {
+	now := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	var sent []string
+	a := newTestAlerter(t, &now, &sent)
+
+	a.handle(scraper.LayoutBreak{Domain: "co.uk", Reason: "no items", HTML: "<html>results</html>"})
+
+	path := filepath.Join(a.dir, "20210626-060000-co.uk.html")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected the page to be saved: %v", err)
+	}
+
+	if string(content) != "<html>results</html>" {
+		t.Errorf("unexpected saved page %q", content)
+	}
+
+	if len(sent) != 1 || !strings.Contains(sent[0], path) {
+		t.Errorf("expected the alert to give the saved page, got %v", sent)
+	}
+}
+
+func TestLayoutAlerterConcurrentUse(t *testing.T) {
+	now := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	var sent []string
+	a := newTestAlerter(t, &now, &sent)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.handle(scraper.LayoutBreak{Domain: "com", Reason: "no items"})
+		}()
+	}
+	wg.Wait()
+
+	if len(sent) != 1 {
+		t.Errorf("expected 1 alert, got %d", len(sent))
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"ebay-watchdog/cache"
 	"ebay-watchdog/config"
+	"ebay-watchdog/filter"
 	"ebay-watchdog/scraper"
 	"ebay-watchdog/web"
+	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -14,30 +16,79 @@ import (
 )
 
 type Coordinator struct {
-	Scraper     *scraper.Scraper
-	SleepPeriod time.Duration
-	Tpl         *template.Template
+	Scraper *scraper.Scraper
+	// Enricher is nil when the enrichment is disabled.
+	Enricher *scraper.Enricher
+	// Filters decide which of the new listings are notified.
+	Filters filter.Set
+	// SoldInterval is the period between two scrapings of the sold listings, when the deals are enabled.
+	SoldInterval time.Duration
+	SleepPeriod  time.Duration
+	Tpl          *template.Template
+	// WatchButtons adds a Watch button to the notifications, to add the listings to the watchlist.
+	WatchButtons bool
+	// Client sends the notifications.
+	Client *web.Client
+
+	// blockedLoops is the number of consecutive scraping loops in which eBay blocked the requests.
+	blockedLoops int
 }
 
 func NewCoordinator(
-	searchItems []config.SearchItem,
+	cfg config.Config,
 	sleepPeriod time.Duration,
 	tpl *template.Template,
-) *Coordinator {
-	searchURLs := buildSearchURLs(searchItems)
-	s := scraper.NewScraper(searchURLs)
+	client *web.Client,
+) (*Coordinator, error) {
+	searchURLs, errs := buildSearchURLs(cfg.Searches, cfg.MaxPages)
+	filters, filterErrs := buildFilters(cfg, searchURLs, client)
+	errs = append(errs, filterErrs...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
 
-	return &Coordinator{
-		Scraper:     s,
-		SleepPeriod: sleepPeriod,
-		Tpl:         tpl,
+	s := scraper.NewScraper(searchURLs, buildSources(cfg, client))
+
+	var enricher *scraper.Enricher
+	if cfg.Enrichment.Enabled {
+		enricher = scraper.NewEnricher(
+			cfg.Enrichment.Concurrency,
+			time.Duration(cfg.Enrichment.Delay)*time.Millisecond,
+			cfg.Enrichment.Description,
+			client,
+		)
+	}
+
+	soldInterval := time.Duration(cfg.Deals.Interval) * time.Minute
+	if soldInterval == 0 {
+		soldInterval = 6 * time.Hour
 	}
+
+	return &Coordinator{
+		Scraper:      s,
+		Enricher:     enricher,
+		Filters:      filters,
+		SoldInterval: soldInterval,
+		SleepPeriod:  sleepPeriod,
+		Tpl:          tpl,
+		WatchButtons: cfg.Watchlist.Enabled && cfg.Watchlist.Buttons,
+		Client:       client,
+	}, nil
 }
 
 func (c *Coordinator) Start(
 	scrapedURLs map[string]cache.CachedListing,
+	notified []cache.NotifiedListing,
+	sold map[string][]cache.SoldListing,
 ) {
+	c.Filters.Relists.Load(notified)
+	c.Filters.Deals.Load(sold)
+
+	var soldAt time.Time
 	for {
+		// The sites which blocked the previous loop are requested again.
+		c.Client.Unblock()
+
 		listings, lastItems, err := c.Scraper.Scrape(scrapedURLs)
 		if err != nil {
 			log.Println("error while scraping new listings, skipping", err)
@@ -45,6 +96,15 @@ func (c *Coordinator) Start(
 			continue
 		}
 
+		c.reportFetchErrors(newFetchReport(lastItems))
+		// The sold listings, item pages and thumbnails of the blocked sites are not requested during this loop.
+		blockSites(c.Client, lastItems)
+
+		if c.Filters.Deals != nil && time.Since(soldAt) >= c.SoldInterval {
+			c.updateSold()
+			soldAt = time.Now()
+		}
+
 		scrapedURLs = buildCache(lastItems, scrapedURLs)
 
 		err = cache.UpdateCache(scrapedURLs)
@@ -52,26 +112,91 @@ func (c *Coordinator) Start(
 			log.Println("error while updating scraped URLs, skipping", err)
 		}
 
-		sendToTelegram(listings, c.Tpl)
+		// The filtered out listings are already in the cache, so they are not evaluated again.
+		listings = c.Filters.Apply(listings)
 
-		time.Sleep(c.SleepPeriod)
+		if c.Enricher != nil {
+			listings = c.Enricher.Enrich(listings)
+		}
+
+		listings = c.Filters.ApplyRules(listings)
+		listings = c.Filters.ApplySellers(listings)
+
+		if c.Filters.Relists != nil {
+			listings = c.Filters.Relists.Apply(listings)
+			err = cache.UpdateNotified(c.Filters.Relists.Notified())
+			if err != nil {
+				log.Println("error while updating notified listings, skipping", err)
+			}
+		}
+
+		sendToTelegram(c.Client, listings, c.Tpl, c.WatchButtons)
+
+		time.Sleep(nextSleep(c.SleepPeriod, c.blockedLoops))
 	}
 
 }
 
+// reportFetchErrors logs the errors of the search page requests of the last scraping loop. While eBay blocks the
+// requests, the scraping loops are spaced out, and the operator is alerted when the block starts.
+func (c *Coordinator) reportFetchErrors(report fetchReport) {
+	if !report.empty() {
+		log.Printf("Some search pages could not be fetched: %s\n", report)
+	}
+
+	if len(report.blocked) == 0 {
+		if c.blockedLoops > 0 {
+			log.Println("eBay does not block the requests anymore")
+		}
+		c.blockedLoops = 0
+		return
+	}
+
+	c.blockedLoops++
+	sleep := nextSleep(c.SleepPeriod, c.blockedLoops)
+	log.Printf("eBay blocked the requests to %s, waiting %s before the next scraping\n", strings.Join(report.blocked, ", "), sleep)
+
+	if c.blockedLoops == 1 {
+		msg := fmt.Sprintf(
+			"ebay-watchdog: eBay blocked the requests to %s. The scraping is slowed down until they are accepted again.",
+			strings.Join(report.blocked, ", "),
+		)
+		if err := sendOperatorMessage(c.Client, msg); err != nil {
+			log.Println("could not send the blocked requests alert", err)
+		}
+	}
+}
+
+// updateSold scrapes the sold listings of the searches, adds them to the price distributions, and persists them.
+func (c *Coordinator) updateSold() {
+	for URL, listings := range c.Scraper.ScrapeSold() {
+		c.Filters.Deals.Add(URL, listings)
+	}
+
+	err := cache.UpdateSold(c.Filters.Deals.Sold())
+	if err != nil {
+		log.Println("error while updating sold listings, skipping", err)
+	}
+}
+
 // buildCache returns a map[string]cache.CachedListing, ready to be persisted into the cache, from the given
-// map[string]scraper.Listing which comes from the last scraping, and the map[string]cache.CachedListing which is the
-// previous cache.
+// map[string]scraper.ScrapedSearch which comes from the last scraping, and the map[string]cache.CachedListing which is
+// the previous cache.
 // It uses data from both maps to build the new cache.
-func buildCache(lastItems map[string]scraper.Listing, scrapedURLs map[string]cache.CachedListing) map[string]cache.CachedListing {
+func buildCache(lastItems map[string]scraper.ScrapedSearch, scrapedURLs map[string]cache.CachedListing) map[string]cache.CachedListing {
+	now := time.Now()
 	lastScrapedURLs := make(map[string]cache.CachedListing)
-	for key, listing := range lastItems {
-		toPersist := cache.CachedListing{
-			URL:  listing.URL,
-			Date: listing.Date,
+	for key, search := range lastItems {
+		toPersist, isKnownURL := scrapedURLs[key]
+		if search.Last != nil {
+			toPersist.URL = search.Last.URL
+			toPersist.Date = search.Last.Date
+		} else if !isKnownURL && len(search.Alerted) == 0 {
+			// Without a last listing, the next scraping could not tell where to stop.
+			continue
 		}
 
-		lastScrapedURLs[key] = toPersist
+		lastScrapedURLs[key] = toPersist.AddSeen(search.Seen, now).AddAlerted(search.Alerted, now)
 	}
 
 	for k, v := range scrapedURLs {
@@ -83,7 +208,7 @@ func buildCache(lastItems map[string]scraper.Listing, scrapedURLs map[string]cac
 	return lastScrapedURLs
 }
 
-func sendToTelegram(listings []scraper.Listing, tpl *template.Template) {
+func sendToTelegram(client *web.Client, listings []scraper.Listing, tpl *template.Template, watchButtons bool) {
 	for _, listing := range listings {
 		buf := &bytes.Buffer{}
 		err := tpl.Execute(buf, listing)
@@ -98,10 +223,17 @@ func sendToTelegram(listings []scraper.Listing, tpl *template.Template) {
 		// Double quotes are not correctly parsed by Telegram
 		msg = strings.ReplaceAll(msg, `"`, "")
 
-		err = web.SendTelegramMessage(
+		var buttons []web.TelegramButton
+		if watchButtons {
+			buttons = append(buttons, watchButton(listing))
+		}
+
+		err = client.SendTelegramMessage(
 			os.Getenv("TELEGRAM_TOKEN"),
 			os.Getenv("TELEGRAM_CHAT_ID"),
 			msg,
+			listing.Silent,
+			buttons...,
 		)
 		if err != nil {
 			log.Println("could not send Telegram message", err)
@@ -110,15 +242,230 @@ func sendToTelegram(listings []scraper.Listing, tpl *template.Template) {
 }
 
 // buildSearchURLs takes a list []config.SearchItem from the config, and returns a list []scraper.SearchURL directly
-// usable by the scraper.
-func buildSearchURLs(searchItems []config.SearchItem) []scraper.SearchURL {
+// usable by the scraper, in the same order. The search URLs are normalised, and all the problems of the searches are
+// returned together.
+func buildSearchURLs(searchItems []config.SearchItem, maxPages int) ([]scraper.SearchURL, ConfigErrors) {
+	var errs ConfigErrors
 	searchURLs := make([]scraper.SearchURL, len(searchItems))
 	for i, s := range searchItems {
+		URL, searchErrs := validateSearch(s, s.SearchURL(searchHost(s.Domains)))
+		for _, err := range searchErrs {
+			errs = append(errs, fmt.Errorf("search #%d: %v", i+1, err))
+		}
+
+		pages := s.MaxPages
+		if pages == 0 {
+			pages = maxPages
+		}
+
 		searchURLs[i] = scraper.SearchURL{
-			URL:     s.URL,
-			Domains: s.Domains,
+			URL:             URL,
+			Domains:         s.Domains,
+			Source:          s.Source,
+			FlagFewerWords:  s.FlagFewerWords,
+			NotifySponsored: s.NotifySponsored,
+			MaxPages:        pages,
+			Mode:            s.Mode,
+			Ending: scraper.Ending{
+				Window:   time.Duration(s.Ending.Window) * time.Minute,
+				MaxBids:  s.Ending.MaxBids,
+				MaxPrice: s.Ending.MaxPrice,
+			},
+		}
+	}
+
+	return searchURLs, errs
+}
+
+// buildFilters returns the exchange rates, the seller lists, the global filter, and the filters and rules of the given
+// searches, built from the config. The searches must be in the order of the config. All the invalid filters are returned together.
+// The relist detector downloads the images with the given client.
+func buildFilters(cfg config.Config, searchURLs []scraper.SearchURL, client *web.Client) (filter.Set, ConfigErrors) {
+	var errs ConfigErrors
+	var rates *filter.Rates
+	if cfg.Currency.Base != "" {
+		var err error
+		rates, err = filter.NewRates(cfg.Currency.Base, cfg.Currency.Rates, cfg.Currency.File)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("currency: %v", err))
+		}
+	}
+
+	global, err := filter.NewFilter(filterRules(cfg.Filter), rates)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("filter: %v", err))
+	}
+
+	set := filter.Set{
+		Global:   global,
+		Searches: make(map[string]*filter.Filter),
+		Rules:    make(map[string]*filter.Engine),
+		Rates:    rates,
+	}
+
+	searchSellers := make(map[string]filter.SellerList)
+
+	for i, s := range cfg.Searches {
+		f, err := filter.NewFilter(filterRules(s.Filter), rates)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("search #%d: filter: %v", i+1, err))
+		} else {
+			set.Searches[searchURLs[i].URL] = f
+		}
+
+		rules := make([]filter.Rule, len(s.Rules))
+		for j, r := range s.Rules {
+			rules[j] = filter.Rule{When: r.When, Action: filter.Action(r.Action), Tag: r.Tag}
+		}
+
+		engine, err := filter.NewEngine(rules, rates)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("search #%d (%s): %v", i+1, searchURLs[i].URL, err))
+		} else {
+			set.Rules[searchURLs[i].URL] = engine
+		}
+
+		if s.Sellers.File != "" {
+			errs = append(errs, fmt.Errorf("search #%d: the sellers file can only be set in the global [sellers]", i+1))
 		}
+		searchSellers[searchURLs[i].URL] = filter.SellerList{Allow: s.Sellers.Allow, Deny: s.Sellers.Deny}
 	}
 
-	return searchURLs
+	globalSellers := filter.SellerList{Allow: cfg.Sellers.Allow, Deny: cfg.Sellers.Deny}
+	sellers, err := filter.NewSellers(globalSellers, searchSellers, cfg.Sellers.File)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("sellers: %v", err))
+	}
+	set.Sellers = sellers
+
+	if cfg.Deals.Enabled {
+		window := time.Duration(cfg.Deals.Window) * 24 * time.Hour
+		deals, err := filter.NewDeals(window, rates)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deals: %v", err))
+		}
+		set.Deals = deals
+	} else if usesPercentile(cfg) {
+		errs = append(errs, fmt.Errorf("max_percentile needs the deals to be enabled"))
+	}
+
+	if cfg.Relist.Enabled {
+		window := time.Duration(cfg.Relist.Window) * time.Hour
+		var images *web.Client
+		if cfg.Relist.Images {
+			images = client
+		}
+		relists, err := filter.NewRelists(cfg.Relist.Action, window, cfg.Relist.Similarity, images)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("relist: %v", err))
+		}
+		set.Relists = relists
+	}
+
+	return set, errs
+}
+
+// usesPercentile returns whether the global filter or a search filter bounds the percentile of the prices.
+func usesPercentile(cfg config.Config) bool {
+	if cfg.Filter.MaxPercentile != 0 {
+		return true
+	}
+
+	for _, s := range cfg.Searches {
+		if s.Filter.MaxPercentile != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterRules converts the given filter from the config into filter rules.
+func filterRules(f config.Filter) filter.Rules {
+	return filter.Rules{
+		Include:       f.Include,
+		Exclude:       f.Exclude,
+		Regex:         f.Regex,
+		ExcludeRegex:  f.ExcludeRegex,
+		Condition:     f.Condition,
+		MinPrice:      f.MinPrice,
+		MaxPrice:      f.MaxPrice,
+		MaxPercentile: f.MaxPercentile,
+	}
+}
+
+// searchHost returns the host of the eBay site of the first given domain, on which the structured searches are built.
+// The scraper then moves the search to each domain.
+func searchHost(domains []string) string {
+	if len(domains) > 0 {
+		if site, ok := scraper.LookupSite(domains[0]); ok {
+			return site.Host
+		}
+	}
+
+	site, _ := scraper.LookupSite("com")
+	return site.Host
+}
+
+// buildSources returns the listing sources available to the scraper, by name, all making their requests with the
+// given client.
+func buildSources(cfg config.Config, client *web.Client) map[string]scraper.Source {
+	htmlSource := scraper.NewHTMLSource(buildSelectorProfiles(cfg.Selectors), client)
+
+	alerter := newLayoutAlerter(cfg.Diagnostics.Dir, time.Duration(cfg.Diagnostics.AlertInterval)*time.Second, client)
+	htmlSource.OnLayoutBreak = alerter.handle
+
+	browseClient := web.NewBrowseClient(cfg.API.URL, os.Getenv("EBAY_CLIENT_ID"), os.Getenv("EBAY_CLIENT_SECRET"))
+	browseClient.HTTPClient = client.API
+
+	return map[string]scraper.Source{
+		scraper.SourceHTML: htmlSource,
+		scraper.SourceAPI:  scraper.NewAPISource(browseClient),
+		scraper.SourceRSS:  scraper.NewRSSSource(client),
+	}
+}
+
+// buildSelectorProfiles takes the selector profiles from the config, and returns a list []scraper.SelectorProfile
+// directly usable by the scraper.
+func buildSelectorProfiles(profiles []config.SelectorProfile) []scraper.SelectorProfile {
+	res := make([]scraper.SelectorProfile, len(profiles))
+	for i, p := range profiles {
+		res[i] = scraper.SelectorProfile{
+			Version:   p.Version,
+			Domains:   p.Domains,
+			Container: p.Container,
+			Item:      p.Item,
+			Link:      p.Link,
+			Title:     p.Title,
+			Subtitle:  p.Subtitle,
+			Price:     p.Price,
+			Date:      p.Date,
+			Image:     p.Image,
+			Condition: p.Condition,
+			Shipping:  p.Shipping,
+			Location:  p.Location,
+			Seller:    p.Seller,
+			TimeLeft:  p.TimeLeft,
+			Bids:      p.Bids,
+			Count:     p.Count,
+			Boundary:  p.Boundary,
+			Sponsored: p.Sponsored,
+			Next:      p.Next,
+		}
+	}
+
+	return res
+}
+
+// NewHTTPClient returns the HTTP client shared by the scraper and the notifications, configured by the given config.
+func NewHTTPClient(cfg config.HTTP) *web.Client {
+	return web.NewClient(web.ClientOptions{
+		PageTimeout:         time.Duration(cfg.PageTimeout) * time.Second,
+		APITimeout:          time.Duration(cfg.APITimeout) * time.Second,
+		TelegramTimeout:     time.Duration(cfg.TelegramTimeout) * time.Second,
+		MaxIdleConns:        cfg.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:     time.Duration(cfg.IdleConnTimeout) * time.Second,
+		DisableHTTP2:        cfg.DisableHTTP2,
+	})
 }
@@ -0,0 +1,125 @@
+package coordinator
+
+import (
+	"ebay-watchdog/scraper"
+	"ebay-watchdog/web"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxBlockedSleep is the maximum period between two scraping loops while eBay blocks the requests.
+const maxBlockedSleep = time.Hour
+
+// fetchReport sums up the errors of the search page requests of a scraping loop, by kind.
+type fetchReport struct {
+	// blocked are the eBay sites which blocked the requests, sorted by host. The searches stopped by the Browse API
+	// rate limit are counted apart, as they do not slow down the scraping of the sites.
+	blocked     []string
+	rateLimited int
+	timeouts    int
+	statuses    int
+	parse       int
+	other       int
+}
+
+// newFetchReport returns the report of the errors of the given searches.
+func newFetchReport(lastItems map[string]scraper.ScrapedSearch) fetchReport {
+	var r fetchReport
+	blocked := make(map[string]bool)
+	for _, search := range lastItems {
+		if search.Err == nil {
+			continue
+		}
+
+		var blockedErr *web.BlockedError
+		var timeoutErr *web.TimeoutError
+		var statusErr *web.StatusError
+		var parseErr *web.ParseError
+		switch {
+		case errors.As(search.Err, &blockedErr):
+			if host, isSite := blockedHost(blockedErr); isSite {
+				blocked[host] = true
+			} else {
+				r.rateLimited++
+			}
+		case errors.As(search.Err, &timeoutErr):
+			r.timeouts++
+		case errors.As(search.Err, &statusErr):
+			r.statuses++
+		case errors.As(search.Err, &parseErr):
+			r.parse++
+		default:
+			r.other++
+		}
+	}
+
+	for host := range blocked {
+		r.blocked = append(r.blocked, host)
+	}
+	sort.Strings(r.blocked)
+
+	return r
+}
+
+// blockedHost returns the host which blocked the request of the given error, and whether it is an eBay site rather
+// than the Browse API.
+func blockedHost(blockedErr *web.BlockedError) (string, bool) {
+	host := blockedErr.URL
+	if u, err := url.Parse(blockedErr.URL); err == nil {
+		host = u.Host
+	}
+
+	_, isSite := scraper.LookupSiteByHost(host)
+	return host, isSite
+}
+
+// blockSites records on the given client the eBay sites which blocked the searches, so that the other requests to
+// these sites are skipped. The Browse API rate limit does not block the sites.
+func blockSites(client *web.Client, lastItems map[string]scraper.ScrapedSearch) {
+	for URL, search := range lastItems {
+		var blockedErr *web.BlockedError
+		if !errors.As(search.Err, &blockedErr) {
+			continue
+		}
+
+		if _, isSite := blockedHost(blockedErr); isSite {
+			client.Block(URL, blockedErr)
+		}
+	}
+}
+
+// empty returns whether no request failed.
+func (r fetchReport) empty() bool {
+	return len(r.blocked) == 0 && r.rateLimited == 0 && r.timeouts == 0 && r.statuses == 0 && r.parse == 0 && r.other == 0
+}
+
+func (r fetchReport) String() string {
+	blocked := "no site"
+	if len(r.blocked) > 0 {
+		blocked = strings.Join(r.blocked, ", ")
+	}
+
+	return fmt.Sprintf(
+		"blocked by %s, %d API rate limit(s), %d timeout(s), %d status error(s), %d parse error(s), %d other error(s)",
+		blocked, r.rateLimited, r.timeouts, r.statuses, r.parse, r.other,
+	)
+}
+
+// nextSleep returns the period before the next scraping loop: the given period, doubled for each of the given
+// consecutive loops in which eBay blocked the requests, up to maxBlockedSleep.
+func nextSleep(period time.Duration, blockedLoops int) time.Duration {
+	sleep := period
+	for i := 0; i < blockedLoops && sleep < maxBlockedSleep; i++ {
+		sleep *= 2
+	}
+
+	if sleep > maxBlockedSleep && period < maxBlockedSleep {
+		return maxBlockedSleep
+	}
+
+	return sleep
+}
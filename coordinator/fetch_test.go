@@ -0,0 +1,74 @@
+package coordinator
+
+import (
+	"ebay-watchdog/scraper"
+	"ebay-watchdog/web"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewFetchReport(t *testing.T) {
+	lastItems := map[string]scraper.ScrapedSearch{
+		"a": {},
+		"b": {Err: fmt.Errorf("could not make request: %w", &web.BlockedError{URL: "https://www.ebay.de/sch/i.html?_nkw=a"})},
+		"c": {Err: &web.BlockedError{URL: "https://www.ebay.de/sch/i.html?_nkw=c"}},
+		"d": {Err: &web.BlockedError{URL: "https://www.ebay.co.uk/sch/i.html?_nkw=d"}},
+		"e": {Err: &web.TimeoutError{URL: "https://www.ebay.com/sch/i.html?_nkw=e"}},
+		"f": {Err: &web.StatusError{URL: "https://www.ebay.com/sch/i.html?_nkw=f", StatusCode: 503}},
+		"g": {Err: &web.ParseError{URL: "https://www.ebay.com/sch/i.html?_nkw=g&_rss=1"}},
+		"h": {Err: fmt.Errorf("connection refused")},
+		"i": {Err: fmt.Errorf("could not search the Browse API: %w", &web.BlockedError{URL: "https://api.ebay.com/buy/browse/v1/item_summary/search"})},
+	}
+
+	report := newFetchReport(lastItems)
+	expected := fetchReport{blocked: []string{"www.ebay.co.uk", "www.ebay.de"}, rateLimited: 1, timeouts: 1, statuses: 1, parse: 1, other: 1}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("got report %+v, expected %+v", report, expected)
+	}
+
+	if !newFetchReport(map[string]scraper.ScrapedSearch{"a": {}}).empty() {
+		t.Errorf("expected an empty report without errors")
+	}
+}
+
+func TestBlockSites(t *testing.T) {
+	client := web.NewClient(web.ClientOptions{})
+	blockSites(client, map[string]scraper.ScrapedSearch{
+		"https://www.ebay.com/sch/i.html?_nkw=a": {
+			Err: fmt.Errorf("could not search the Browse API: %w", &web.BlockedError{URL: "https://api.ebay.com/buy/browse/v1/item_summary/search", StatusCode: 429}),
+		},
+		"https://www.ebay.de/sch/i.html?_nkw=b": {
+			Err: &web.BlockedError{URL: "https://www.ebay.de/sch/i.html?_nkw=b", StatusCode: 403},
+		},
+	})
+
+	if client.Blocked("https://www.ebay.com/itm/1") != nil {
+		t.Errorf("expected the site of the rate limited API search not to be blocked")
+	}
+
+	if blockedErr := client.Blocked("https://www.ebay.de/itm/2"); blockedErr == nil || blockedErr.StatusCode != 403 {
+		t.Errorf("expected the site of the blocked search to be blocked, got %v", blockedErr)
+	}
+}
+
+func TestNextSleep(t *testing.T) {
+	tests := []struct {
+		period       time.Duration
+		blockedLoops int
+		expected     time.Duration
+	}{
+		{time.Minute, 0, time.Minute},
+		{time.Minute, 1, 2 * time.Minute},
+		{time.Minute, 3, 8 * time.Minute},
+		{time.Minute, 10, time.Hour},
+		{2 * time.Hour, 2, 2 * time.Hour},
+	}
+
+	for _, test := range tests {
+		if got := nextSleep(test.period, test.blockedLoops); got != test.expected {
+			t.Errorf("nextSleep(%s, %d) = %s, expected %s", test.period, test.blockedLoops, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package coordinator
+
+import (
+	"ebay-watchdog/config"
+	"ebay-watchdog/scraper"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// newestFirstSort and endingSoonestSort are the values of the _sop search parameter listing the newly listed items
+// first, and the items ending soonest first.
+const (
+	newestFirstSort   = "10"
+	endingSoonestSort = "1"
+)
+
+// ConfigErrors are all the problems found in the config.
+type ConfigErrors []error
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d problem(s) in the config:\n- %s", len(e), strings.Join(msgs, "\n- "))
+}
+
+// validateSearch checks the given search, and returns its URL normalised: on the www host of an eBay site, sorted as
+// required by the mode of the search. It returns all the problems found in the search.
+func validateSearch(s config.SearchItem, URL string) (string, []error) {
+	var errs []error
+	if err := s.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, d := range s.Domains {
+		if _, ok := scraper.LookupSite(d); !ok {
+			errs = append(errs, fmt.Errorf("unsupported domain %s, expected one of %s", d, strings.Join(scraper.SiteDomains(), ", ")))
+		}
+	}
+
+	switch s.Source {
+	case "", scraper.SourceHTML, scraper.SourceAPI, scraper.SourceRSS:
+	default:
+		errs = append(errs, fmt.Errorf("unknown source %s", s.Source))
+	}
+
+	switch s.Mode {
+	case "", scraper.ModeNewest:
+	case scraper.ModeEnding:
+		if s.Source != "" && s.Source != scraper.SourceHTML {
+			errs = append(errs, fmt.Errorf("the ending mode is only supported by the html source"))
+		}
+		if s.Ending.Window < 0 || s.Ending.MaxBids < 0 || s.Ending.MaxPrice < 0 {
+			errs = append(errs, fmt.Errorf("invalid ending window, max bids or max price"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown mode %s, expected newest or ending", s.Mode))
+	}
+
+	normalised, err := normaliseSearchURL(URL, s.Mode)
+	if err != nil {
+		errs = append(errs, err)
+	} else if s.Source == scraper.SourceAPI && !hasKeywordsOrCategory(normalised) {
+		errs = append(errs, fmt.Errorf("the api source needs keywords or a category"))
+	}
+
+	return normalised, errs
+}
+
+// hasKeywordsOrCategory returns whether the given search URL has keywords or a category, which the Browse API
+// requires.
+func hasKeywordsOrCategory(URL string) bool {
+	u, err := url.Parse(URL)
+	if err != nil {
+		return false
+	}
+
+	query := u.Query()
+	category := query.Get("_sacat")
+	return strings.TrimSpace(query.Get("_nkw")) != "" || (category != "" && category != "0")
+}
+
+// normaliseSearchURL returns the given eBay search URL on the www host of its eBay site, with the newly listed items
+// first, or with the auctions ending soonest first in the ending mode. The URL is returned as is when it is already
+// normalised.
+func normaliseSearchURL(URL string, mode string) (string, error) {
+	URL = strings.TrimSpace(URL)
+	u, err := url.Parse(URL)
+	if err != nil {
+		return URL, fmt.Errorf("could not parse url %s: %v", URL, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return URL, fmt.Errorf("url %s is not an http(s) url", URL)
+	}
+
+	site, ok := scraper.LookupSiteByHost(u.Hostname())
+	if !ok {
+		return URL, fmt.Errorf("url %s is not on a supported eBay site", URL)
+	}
+
+	changed := false
+	if u.Host != site.Host || u.Scheme != "https" {
+		u.Host = site.Host
+		u.Scheme = "https"
+		changed = true
+	}
+
+	query := u.Query()
+	expSort, sortName := newestFirstSort, "newly listed items"
+	if mode == scraper.ModeEnding {
+		expSort, sortName = endingSoonestSort, "items ending soonest"
+		if query.Get("LH_Auction") != "1" {
+			query.Set("LH_Auction", "1")
+			u.RawQuery = query.Encode()
+			changed = true
+		}
+	}
+
+	if sort := query.Get("_sop"); sort != expSort {
+		if sort != "" {
+			log.Printf("url %s is not sorted by %s, sorting it anyway\n", URL, sortName)
+		}
+		query.Set("_sop", expSort)
+		u.RawQuery = query.Encode()
+		changed = true
+	}
+
+	if !changed {
+		return URL, nil
+	}
+
+	return u.String(), nil
+}
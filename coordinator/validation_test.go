@@ -0,0 +1,130 @@
+package coordinator
+
+import (
+	"ebay-watchdog/config"
+	"ebay-watchdog/scraper"
+	"ebay-watchdog/web"
+	"strings"
+	"testing"
+)
+
+func TestNormaliseSearchURL(t *testing.T) {
+	tests := map[string]string{
+		"https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10":   "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10",
+		"https://m.ebay.com/sch/i.html?_nkw=tape&_sop=10":     "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10",
+		"http://ebay.co.uk/sch/i.html?_nkw=tape&_sop=10":      "https://www.ebay.co.uk/sch/i.html?_nkw=tape&_sop=10",
+		"https://www.ebay.fr/sch/i.html?_nkw=tape":            "https://www.ebay.fr/sch/i.html?_nkw=tape&_sop=10",
+		"https://www.ebay.de/sch/i.html?_nkw=tape&_sop=12":    "https://www.ebay.de/sch/i.html?_nkw=tape&_sop=10",
+		"https://befr.ebay.be/sch/i.html?_nkw=tape&_sop=10":   "https://www.befr.ebay.be/sch/i.html?_nkw=tape&_sop=10",
+		" https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10 ": "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10",
+	}
+
+	for URL, exp := range tests {
+		got, err := normaliseSearchURL(URL, "")
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", URL, err)
+			continue
+		}
+
+		if got != exp {
+			t.Errorf("expected %s but got %s", exp, got)
+		}
+	}
+
+	got, err := normaliseSearchURL("https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10", "ending")
+	if exp := "https://www.ebay.com/sch/i.html?LH_Auction=1&_nkw=tape&_sop=1"; err != nil || got != exp {
+		t.Errorf("expected %s but got %s (%v)", exp, got, err)
+	}
+
+	for _, URL := range []string{"https://www.amazon.com/s?k=tape", "ftp://www.ebay.com/sch", "your url here"} {
+		if _, err := normaliseSearchURL(URL, ""); err == nil {
+			t.Errorf("expected an error for %s", URL)
+		}
+	}
+}
+
+func TestBuildSearchURLsReportsAllErrors(t *testing.T) {
+	searches := []config.SearchItem{
+		{URL: "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10", Domains: []string{"com", "xyz"}},
+		{URL: "https://www.example.com/sch/i.html?_nkw=tape"},
+		{Keywords: "tape", Source: "ftp"},
+		{URL: "https://www.ebay.co.uk/sch/i.html?_nkw=tape&_sop=10"},
+	}
+
+	_, errs := buildSearchURLs(searches, 0)
+	if len(errs) != 3 {
+		t.Errorf("expected 3 errors but got %d: %v", len(errs), errs)
+	}
+
+	searchURLs, errs := buildSearchURLs(searches[3:], 0)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	if len(searchURLs) != 1 || searchURLs[0].URL != searches[3].URL {
+		t.Errorf("unexpected search URLs %+v", searchURLs)
+	}
+}
+
+func TestBuildSearchURLsSource(t *testing.T) {
+	searches := []config.SearchItem{
+		{URL: "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10"},
+		{URL: "https://www.ebay.com/sch/i.html?_nkw=glue&_sop=10", Source: scraper.SourceRSS},
+		{URL: "https://www.ebay.com/sch/i.html?_nkw=rope&_sop=10", Source: scraper.SourceAPI},
+	}
+
+	searchURLs, errs := buildSearchURLs(searches, 0)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	sources := buildSources(config.Config{Searches: searches}, web.DefaultClient)
+	for i, s := range searches {
+		if searchURLs[i].Source != s.Source {
+			t.Errorf("search #%d: got source %q, expected %q", i+1, searchURLs[i].Source, s.Source)
+		}
+
+		if s.Source != "" && sources[searchURLs[i].Source] == nil {
+			t.Errorf("search #%d: no listing source %q", i+1, searchURLs[i].Source)
+		}
+	}
+
+	if _, ok := sources[searchURLs[1].Source].(*scraper.RSSSource); !ok {
+		t.Errorf("expected the rss search to use the RSS source, got %T", sources[searchURLs[1].Source])
+	}
+}
+
+func TestBuildSearchURLsAPISource(t *testing.T) {
+	searches := []config.SearchItem{
+		{Seller: "tape_seller", Source: scraper.SourceAPI},
+		{URL: "https://www.ebay.com/sch/i.html?_ssn=tape_seller&_sacat=0&_sop=10", Source: scraper.SourceAPI},
+		{Seller: "tape_seller", Keywords: "tape", Source: scraper.SourceAPI},
+		{Seller: "tape_seller", Category: 625, Source: scraper.SourceAPI},
+		{Seller: "tape_seller", Source: scraper.SourceRSS},
+	}
+
+	_, errs := buildSearchURLs(searches, 0)
+	if len(errs) != 2 || !strings.HasPrefix(errs[0].Error(), "search #1:") || !strings.HasPrefix(errs[1].Error(), "search #2:") {
+		t.Errorf("expected the 2 api searches without keywords nor category to be rejected, got %v", errs)
+	}
+}
+
+func TestBuildSearchURLsErrorsIdentifySearch(t *testing.T) {
+	searches := []config.SearchItem{
+		{Keywords: "tape"},
+		{Category: 625, MinPrice: 20, MaxPrice: 10},
+		{Seller: "tape_seller", Location: "moon"},
+	}
+
+	_, errs := buildSearchURLs(searches, 0)
+	exp := []string{"search #2: invalid price range 20-10", "search #3: unknown location moon"}
+	if len(errs) != len(exp) {
+		t.Fatalf("expected %d errors but got %v", len(exp), errs)
+	}
+
+	for i, err := range errs {
+		if err.Error() != exp[i] {
+			t.Errorf("expected error %q but got %q", exp[i], err)
+		}
+	}
+}
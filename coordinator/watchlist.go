@@ -0,0 +1,318 @@
+package coordinator
+
+import (
+	"ebay-watchdog/cache"
+	"ebay-watchdog/scraper"
+	"ebay-watchdog/web"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watchCallbackPrefix starts the data of the Watch buttons of the notifications, followed by the location domain and
+// the item ID, e.g. watch:co.uk:402943017690.
+const watchCallbackPrefix = "watch:"
+
+// Watcher checks the item pages of the watchlist periodically, and notifies the changes of price, bids and quantity,
+// the accepted best offers and the ended listings. The watchlist is persisted, so that it can also be edited from the
+// command line while the watchdog runs.
+type Watcher struct {
+	// Interval is the period between two checks of the watchlist.
+	Interval time.Duration
+	// Pause is the period between two item page requests, to prevent getting throttled.
+	Pause time.Duration
+
+	mu     sync.Mutex
+	client *web.Client
+	load   func() ([]cache.WatchedItem, error)
+	save   func([]cache.WatchedItem) error
+	fetch  func(URL string) (scraper.ItemState, error)
+	send   func(msg string) error
+	now    func() time.Time
+}
+
+// NewWatcher returns a watcher checking the item pages and sending the notifications with the given client.
+func NewWatcher(interval time.Duration, client *web.Client) *Watcher {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	return &Watcher{
+		Interval: interval,
+		Pause:    2 * time.Second,
+		client:   client,
+		load:     cache.LoadWatchlist,
+		save:     cache.UpdateWatchlist,
+		fetch: func(URL string) (scraper.ItemState, error) {
+			return scraper.FetchItemState(client, URL)
+		},
+		send: func(msg string) error {
+			return sendWatchlistMessage(client, msg)
+		},
+		now: time.Now,
+	}
+}
+
+// Add adds the given items, item IDs or item page URLs, to the watchlist. It returns the IDs of the added items,
+// without the ones which were already watched.
+func (w *Watcher) Add(refs []string) ([]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	items, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var added []string
+	for _, ref := range refs {
+		ID, URL, err := scraper.ParseItemRef(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		if indexOfItem(items, ID) >= 0 {
+			continue
+		}
+
+		items = append(items, cache.WatchedItem{ID: ID, URL: URL, Available: -1})
+		added = append(added, ID)
+	}
+
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	return added, w.save(items)
+}
+
+// Remove removes the items with the given IDs from the watchlist. It returns the IDs of the removed items.
+func (w *Watcher) Remove(IDs []string) ([]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	items, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, ID := range IDs {
+		if i := indexOfItem(items, strings.TrimSpace(ID)); i >= 0 {
+			items = append(items[:i], items[i+1:]...)
+			removed = append(removed, ID)
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	return removed, w.save(items)
+}
+
+// List returns the watched items.
+func (w *Watcher) List() ([]cache.WatchedItem, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.load()
+}
+
+// Start checks the watchlist forever, every Interval.
+func (w *Watcher) Start() {
+	for {
+		w.check()
+		time.Sleep(w.Interval)
+	}
+}
+
+// check fetches the item page of each watched item, notifies its changes since the last check, and persists the new
+// states. The ended items are removed from the watchlist once notified.
+func (w *Watcher) check() {
+	w.mu.Lock()
+	items, err := w.load()
+	w.mu.Unlock()
+	if err != nil {
+		log.Println("could not load the watchlist, skipping", err)
+		return
+	}
+
+	checked := make(map[string]cache.WatchedItem)
+	ended := make(map[string]bool)
+	for i, item := range items {
+		if i > 0 {
+			time.Sleep(w.Pause)
+		}
+
+		state, err := w.fetch(item.URL)
+		if err != nil {
+			log.Println("could not check watched item, skipping", err)
+			continue
+		}
+
+		if changes := itemChanges(item, state); len(changes) > 0 {
+			title := state.Title
+			if title == "" {
+				title = item.Title
+			}
+
+			msg := fmt.Sprintf("Watched item: %s\n%s\n- %s", title, item.URL, strings.Join(changes, "\n- "))
+			if err := w.send(msg); err != nil {
+				log.Println("could not send the watchlist message", err)
+			}
+		}
+
+		if state.Ended {
+			log.Printf("Watched item %s ended, removing it from the watchlist\n", item.ID)
+			ended[item.ID] = true
+			continue
+		}
+
+		checked[item.ID] = cache.WatchedItem{
+			ID:                item.ID,
+			URL:               item.URL,
+			Title:             state.Title,
+			Price:             state.Price,
+			Bids:              state.Bids,
+			Available:         state.Available,
+			BestOfferAccepted: state.BestOfferAccepted,
+			CheckedAt:         w.now(),
+		}
+	}
+
+	// The watchlist is loaded again, as items may have been added or removed during the check.
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	items, err = w.load()
+	if err != nil {
+		log.Println("could not load the watchlist, skipping", err)
+		return
+	}
+
+	var res []cache.WatchedItem
+	for _, item := range items {
+		if ended[item.ID] {
+			continue
+		}
+		if c, ok := checked[item.ID]; ok {
+			item = c
+		}
+		res = append(res, item)
+	}
+
+	err = w.save(res)
+	if err != nil {
+		log.Println("could not update the watchlist", err)
+	}
+}
+
+// itemChanges returns the notable changes between the given watched item and its new state. The first check of an
+// item only records its state, unless it already ended.
+func itemChanges(item cache.WatchedItem, state scraper.ItemState) []string {
+	var changes []string
+	if state.Ended {
+		changes = append(changes, "the listing has ended")
+	}
+
+	if item.CheckedAt.IsZero() {
+		return changes
+	}
+
+	if state.Price != "" && item.Price != "" && state.Price != item.Price {
+		changes = append(changes, fmt.Sprintf("price: %s -> %s", item.Price, state.Price))
+	}
+
+	if state.Bids > item.Bids {
+		changes = append(changes, fmt.Sprintf("new bids: %d -> %d", item.Bids, state.Bids))
+	}
+
+	if state.BestOfferAccepted && !item.BestOfferAccepted {
+		changes = append(changes, "a best offer was accepted")
+	}
+
+	if state.Available >= 0 && item.Available >= 0 && state.Available != item.Available {
+		changes = append(changes, fmt.Sprintf("quantity available: %d -> %d", item.Available, state.Available))
+	}
+
+	return changes
+}
+
+// ListenTelegram adds the items of the pressed Watch buttons of the notifications to the watchlist, forever. Only
+// the buttons of the given chat are accepted.
+func (w *Watcher) ListenTelegram(token string, chatID string) {
+	offset := 0
+	for {
+		callbacks, next, err := w.client.GetTelegramCallbacks(token, offset, 30*time.Second)
+		if err != nil {
+			log.Println("could not get the Telegram button presses, retrying", err)
+			time.Sleep(w.Interval)
+			continue
+		}
+		offset = next
+
+		for _, c := range callbacks {
+			if c.ChatID != chatID || !strings.HasPrefix(c.Data, watchCallbackPrefix) {
+				continue
+			}
+
+			answer := "Added to the watchlist"
+			_, err := w.Add([]string{watchCallbackRef(c.Data)})
+			if err != nil {
+				log.Println("could not add the item to the watchlist", err)
+				answer = "Could not add the item to the watchlist"
+			}
+
+			if err := w.client.AnswerTelegramCallback(token, c.ID, answer); err != nil {
+				log.Println("could not answer the Telegram button press", err)
+			}
+		}
+	}
+}
+
+// watchButton returns the Watch button of the notification of the given listing.
+func watchButton(listing scraper.Listing) web.TelegramButton {
+	domain := "com"
+	if u, err := url.Parse(listing.URL); err == nil {
+		if site, ok := scraper.LookupSiteByHost(u.Host); ok {
+			domain = site.Domain
+		}
+	}
+
+	return web.TelegramButton{Text: "Watch", Data: watchCallbackPrefix + domain + ":" + listing.ItemID()}
+}
+
+// watchCallbackRef returns the item page URL of the given Watch button data.
+func watchCallbackRef(data string) string {
+	ref := strings.TrimPrefix(data, watchCallbackPrefix)
+	i := strings.LastIndex(ref, ":")
+	if i < 0 {
+		return ref
+	}
+
+	return scraper.ItemURL(ref[i+1:], ref[:i])
+}
+
+// indexOfItem returns the index of the item with the given ID in the given items, or -1.
+func indexOfItem(items []cache.WatchedItem, ID string) int {
+	for i, item := range items {
+		if item.ID == ID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// sendWatchlistMessage sends the given watchlist message to the notifications chat.
+func sendWatchlistMessage(client *web.Client, msg string) error {
+	// Double quotes are not correctly parsed by Telegram
+	msg = strings.ReplaceAll(msg, `"`, "")
+
+	return client.SendTelegramMessage(os.Getenv("TELEGRAM_TOKEN"), os.Getenv("TELEGRAM_CHAT_ID"), msg, false)
+}
@@ -0,0 +1,133 @@
+package coordinator
+
+import (
+	"ebay-watchdog/cache"
+	"ebay-watchdog/scraper"
+	"ebay-watchdog/web"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestWatcher returns a Watcher keeping its watchlist in memory, serving the given item states by URL, and
+// recording the messages sent.
+func newTestWatcher(items []cache.WatchedItem, states map[string]scraper.ItemState, sent *[]string) *Watcher {
+	w := NewWatcher(time.Minute, web.DefaultClient)
+	w.Pause = 0
+	w.load = func() ([]cache.WatchedItem, error) {
+		return append([]cache.WatchedItem(nil), items...), nil
+	}
+	w.save = func(saved []cache.WatchedItem) error {
+		items = saved
+		return nil
+	}
+	w.fetch = func(URL string) (scraper.ItemState, error) {
+		state, ok := states[URL]
+		if !ok {
+			return scraper.ItemState{}, fmt.Errorf("unknown item %s", URL)
+		}
+		return state, nil
+	}
+	w.send = func(msg string) error {
+		*sent = append(*sent, msg)
+		return nil
+	}
+
+	return w
+}
+
+func TestWatcherCheck(t *testing.T) {
+	checkedAt := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	items := []cache.WatchedItem{
+		{ID: "1", URL: "https://www.ebay.com/itm/1", Price: "US $10.00", Bids: 1, Available: -1, CheckedAt: checkedAt},
+		{ID: "2", URL: "https://www.ebay.com/itm/2", Price: "US $5.00", Available: 3, CheckedAt: checkedAt},
+		{ID: "3", URL: "https://www.ebay.com/itm/3", Price: "US $7.00", Available: -1, CheckedAt: checkedAt},
+		{ID: "4", URL: "https://www.ebay.com/itm/4", Available: -1},
+		{ID: "5", URL: "https://www.ebay.com/itm/5", Price: "US $1.00", Available: -1, CheckedAt: checkedAt},
+	}
+	states := map[string]scraper.ItemState{
+		"https://www.ebay.com/itm/1": {Title: "Auction", Price: "US $12.00", Bids: 3, Available: -1},
+		"https://www.ebay.com/itm/2": {Title: "Tape", Price: "US $5.00", Available: 2},
+		"https://www.ebay.com/itm/3": {Title: "Lens", Price: "US $7.00", Available: -1, BestOfferAccepted: true, Ended: true},
+		"https://www.ebay.com/itm/4": {Title: "New", Price: "US $20.00", Available: 1},
+	}
+
+	var sent []string
+	w := newTestWatcher(items, states, &sent)
+	w.check()
+
+	if len(sent) != 3 {
+		t.Fatalf("expected 3 messages but got %d: %v", len(sent), sent)
+	}
+
+	for i, exp := range [][]string{
+		{"Auction", "price: US $10.00 -> US $12.00", "new bids: 1 -> 3"},
+		{"Tape", "quantity available: 3 -> 2"},
+		{"Lens", "the listing has ended", "a best offer was accepted"},
+	} {
+		for _, e := range exp {
+			if !strings.Contains(sent[i], e) {
+				t.Errorf("expected message %q to contain %q", sent[i], e)
+			}
+		}
+	}
+
+	list, _ := w.List()
+	var IDs []string
+	for _, item := range list {
+		IDs = append(IDs, item.ID)
+	}
+	if fmt.Sprint(IDs) != "[1 2 4 5]" {
+		t.Errorf("expected the ended item to be removed, got %v", IDs)
+	}
+
+	if list[2].Price != "US $20.00" || list[2].CheckedAt.IsZero() {
+		t.Errorf("expected the state of the new item to be recorded, got %+v", list[2])
+	}
+
+	if !list[3].CheckedAt.Equal(checkedAt) {
+		t.Errorf("expected the item which could not be checked to be kept as is, got %+v", list[3])
+	}
+}
+
+func TestWatcherAddRemove(t *testing.T) {
+	var sent []string
+	w := newTestWatcher(nil, nil, &sent)
+
+	added, err := w.Add([]string{"402943017690", "https://www.ebay.co.uk/itm/402943017691?hash=item5dd1", "402943017690"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(added) != 2 {
+		t.Errorf("expected 2 added items but got %v", added)
+	}
+
+	if _, err := w.Add([]string{"tape"}); err == nil {
+		t.Errorf("expected an error for an invalid item")
+	}
+
+	removed, err := w.Remove([]string{"402943017690", "1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(removed) != 1 {
+		t.Errorf("expected 1 removed item but got %v", removed)
+	}
+
+	list, _ := w.List()
+	if len(list) != 1 || list[0].URL != "https://www.ebay.co.uk/itm/402943017691" {
+		t.Errorf("unexpected watchlist %+v", list)
+	}
+}
+
+func TestWatchButton(t *testing.T) {
+	b := watchButton(scraper.Listing{URL: "https://www.ebay.co.uk/itm/402943017690?hash=item5dd14682da"})
+	if b.Data != "watch:co.uk:402943017690" {
+		t.Errorf("unexpected button data %s", b.Data)
+	}
+
+	if URL := watchCallbackRef(b.Data); URL != "https://www.ebay.co.uk/itm/402943017690" {
+		t.Errorf("unexpected item URL %s", URL)
+	}
+}
@@ -0,0 +1,122 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Rates converts the prices into a base currency, with a table of exchange rates. The table can be completed by a
+// file, which is read again whenever it changes.
+type Rates struct {
+	// Base is the ISO 4217 code of the currency the prices are converted into, e.g. EUR.
+	Base string
+
+	table   map[string]float64
+	file    string
+	fileMod time.Time
+	// fromFile are the rates read from the file, which take precedence over the table.
+	fromFile map[string]float64
+}
+
+// NewRates returns the given exchange rates into the base currency: the value of one unit of each currency in the base
+// currency, e.g. USD = 0.92 for a EUR base. The given file, if any, is a toml file with the same format, and its rates
+// take precedence over the table.
+func NewRates(base string, table map[string]float64, file string) (*Rates, error) {
+	base = strings.ToUpper(strings.TrimSpace(base))
+	if base == "" {
+		return nil, fmt.Errorf("missing base currency")
+	}
+
+	r := &Rates{
+		Base:  base,
+		table: make(map[string]float64),
+		file:  file,
+	}
+
+	for c, rate := range table {
+		if rate <= 0 {
+			return nil, fmt.Errorf("invalid rate %v for currency %s", rate, c)
+		}
+		r.table[strings.ToUpper(c)] = rate
+	}
+
+	if file != "" {
+		if err := r.Refresh(); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Refresh reads the rates file again if it changed since the last time it was read.
+func (r *Rates) Refresh() error {
+	if r == nil || r.file == "" {
+		return nil
+	}
+
+	info, err := os.Stat(r.file)
+	if err != nil {
+		return fmt.Errorf("could not read rates file %s: %v", r.file, err)
+	}
+
+	if !info.ModTime().After(r.fileMod) {
+		return nil
+	}
+
+	dat, err := ioutil.ReadFile(r.file)
+	if err != nil {
+		return fmt.Errorf("could not read rates file %s: %v", r.file, err)
+	}
+
+	var rates map[string]float64
+	err = toml.Unmarshal(dat, &rates)
+	if err != nil {
+		return fmt.Errorf("could not parse rates file %s: %v", r.file, err)
+	}
+
+	fromFile := make(map[string]float64)
+	for c, rate := range rates {
+		if rate <= 0 {
+			return fmt.Errorf("invalid rate %v for currency %s in %s", rate, c, r.file)
+		}
+		fromFile[strings.ToUpper(c)] = rate
+	}
+
+	if !r.fileMod.IsZero() {
+		log.Printf("Reloaded %d exchange rates from %s\n", len(fromFile), r.file)
+	}
+
+	r.fromFile = fromFile
+	r.fileMod = info.ModTime()
+
+	return nil
+}
+
+// Convert returns the given amount, in the given currency, converted into the base currency. It returns false when
+// the rate of the currency is unknown.
+func (r *Rates) Convert(amount float64, currency string) (float64, bool) {
+	if r == nil {
+		return 0, false
+	}
+
+	currency = strings.ToUpper(currency)
+	if currency == r.Base {
+		return amount, true
+	}
+
+	rate, ok := r.fromFile[currency]
+	if !ok {
+		rate, ok = r.table[currency]
+	}
+	if !ok {
+		return 0, false
+	}
+
+	return amount * rate, true
+}
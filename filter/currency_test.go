@@ -0,0 +1,147 @@
+package filter
+
+import (
+	"ebay-watchdog/scraper"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRatesConvert(t *testing.T) {
+	rates, err := NewRates("eur", map[string]float64{"usd": 0.9, "GBP": 1.2}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		amount   float64
+		currency string
+		exp      float64
+		ok       bool
+	}{
+		{amount: 100, currency: "USD", exp: 90, ok: true},
+		{amount: 100, currency: "GBP", exp: 120, ok: true},
+		{amount: 100, currency: "EUR", exp: 100, ok: true},
+		{amount: 100, currency: "JPY", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := rates.Convert(tt.amount, tt.currency)
+		if ok != tt.ok || got != tt.exp {
+			t.Errorf("expected %v %v but got %v %v for %v %s", tt.exp, tt.ok, got, ok, tt.amount, tt.currency)
+		}
+	}
+
+	if _, err := NewRates("", nil, ""); err == nil {
+		t.Errorf("expected an error without base currency")
+	}
+
+	if _, err := NewRates("EUR", map[string]float64{"USD": 0}, ""); err == nil {
+		t.Errorf("expected an error for a zero rate")
+	}
+}
+
+func TestRatesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "rates.toml")
+	if err := ioutil.WriteFile(file, []byte("USD = 0.8\n"), 0644); err != nil {
+		t.Fatalf("could not write rates file: %v", err)
+	}
+
+	rates, err := NewRates("EUR", map[string]float64{"USD": 0.9, "GBP": 1.2}, file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, _ := rates.Convert(100, "USD"); got != 80 {
+		t.Errorf("expected the rate of the file to take precedence, got %v", got)
+	}
+
+	if got, _ := rates.Convert(100, "GBP"); got != 120 {
+		t.Errorf("expected the rate of the table, got %v", got)
+	}
+
+	if err := ioutil.WriteFile(file, []byte("USD = 0.95\n"), 0644); err != nil {
+		t.Fatalf("could not write rates file: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatalf("could not touch rates file: %v", err)
+	}
+
+	if err := rates.Refresh(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, _ := rates.Convert(100, "USD"); got != 95 {
+		t.Errorf("expected the reloaded rate, got %v", got)
+	}
+}
+
+func TestFilterPriceRange(t *testing.T) {
+	rates, _ := NewRates("EUR", map[string]float64{"USD": 0.9, "GBP": 1.2}, "")
+	f, err := NewFilter(Rules{MinPrice: 50, MaxPrice: 100}, rates)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		listing scraper.Listing
+		exp     bool
+	}{
+		{listing: scraper.Listing{URL: "https://www.ebay.com/itm/1", Price: "$100.00"}, exp: true},
+		{listing: scraper.Listing{URL: "https://www.ebay.com/itm/1", Price: "$120.00"}, exp: false},
+		{listing: scraper.Listing{URL: "https://www.ebay.co.uk/itm/1", Price: "£90.00"}, exp: false},
+		{listing: scraper.Listing{URL: "https://www.ebay.co.uk/itm/1", Price: "£80.00"}, exp: true},
+		{listing: scraper.Listing{URL: "https://www.ebay.fr/itm/1", Price: "40,00 EUR"}, exp: false},
+		{listing: scraper.Listing{URL: "https://www.ebay.pl/itm/1", Price: "400,00 zł"}, exp: true},
+	}
+
+	for _, tt := range tests {
+		got, reason := f.Match(tt.listing)
+		if got != tt.exp {
+			t.Errorf("expected %v but got %v for %s (%s)", tt.exp, got, tt.listing.Price, reason)
+		}
+	}
+
+	if _, err := NewFilter(Rules{MaxPrice: 100}, nil); err == nil {
+		t.Errorf("expected an error for price bounds without base currency")
+	}
+}
+
+func TestSetConvertedPrice(t *testing.T) {
+	rates, _ := NewRates("EUR", map[string]float64{"USD": 0.9}, "")
+	set := Set{Rates: rates}
+
+	got := set.Apply([]scraper.Listing{
+		{URL: "https://www.ebay.com/itm/1", Price: "$100.00"},
+		{URL: "https://www.ebay.fr/itm/2", Price: "100,00 EUR"},
+	})
+
+	if got[0].ConvertedPrice != "90.00 EUR" {
+		t.Errorf("expected 90.00 EUR but got %s", got[0].ConvertedPrice)
+	}
+
+	if got[1].ConvertedPrice != "" {
+		t.Errorf("expected no converted price for a price in the base currency, got %s", got[1].ConvertedPrice)
+	}
+}
+
+func TestExprCurrencyConversion(t *testing.T) {
+	rates, _ := NewRates("EUR", map[string]float64{"USD": 0.9}, "")
+	e, err := NewEngine([]Rule{{When: `price < 100 EUR and base_price >= 89`, Action: ActionDrop}}, rates)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !e.Decide(scraper.Listing{URL: "https://www.ebay.com/itm/1", Price: "$100.00"}).Drop {
+		t.Errorf("expected $100.00 to be converted into 90 EUR")
+	}
+}
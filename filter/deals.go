@@ -0,0 +1,168 @@
+package filter
+
+import (
+	"ebay-watchdog/cache"
+	"ebay-watchdog/scraper"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// minDealSamples is the minimum number of sold listings needed to compare a price.
+const minDealSamples = 5
+
+// Deals keeps a rolling distribution of the prices of the recently sold listings of each search, and compares the
+// prices of the new listings to it.
+type Deals struct {
+	// Window is the period during which the sold listings are kept.
+	Window time.Duration
+
+	rates *Rates
+	sold  map[string][]cache.SoldListing
+	now   func() time.Time
+}
+
+// NewDeals returns an empty price distribution keeping the sold listings during the given window. The prices in
+// different currencies are compared with the given rates, if any.
+func NewDeals(window time.Duration, rates *Rates) (*Deals, error) {
+	if window == 0 {
+		window = 30 * 24 * time.Hour
+	}
+	if window < 0 {
+		return nil, fmt.Errorf("invalid window %v", window)
+	}
+
+	return &Deals{
+		Window: window,
+		rates:  rates,
+		sold:   make(map[string][]cache.SoldListing),
+		now:    time.Now,
+	}, nil
+}
+
+// Load sets the recently sold listings of each search URL, e.g. from the cache.
+func (d *Deals) Load(sold map[string][]cache.SoldListing) {
+	if d == nil || sold == nil {
+		return
+	}
+
+	d.sold = sold
+}
+
+// Sold returns the recently sold listings of each search URL, to be persisted.
+func (d *Deals) Sold() map[string][]cache.SoldListing {
+	if d == nil {
+		return nil
+	}
+
+	return d.sold
+}
+
+// Add adds the given sold listings to the distribution of the given search URL, and forgets the ones sold before the
+// window. The listings already known, and the ones without a price, are ignored.
+func (d *Deals) Add(searchURL string, listings []scraper.Listing) {
+	if d == nil {
+		return
+	}
+
+	now := d.now()
+	known := make(map[string]bool)
+	var sold []cache.SoldListing
+	for _, s := range d.sold[searchURL] {
+		if now.Sub(s.SeenAt) <= d.Window {
+			sold = append(sold, s)
+			known[s.ID] = true
+		}
+	}
+
+	for _, listing := range listings {
+		ID := listing.ItemID()
+		if known[ID] {
+			continue
+		}
+
+		p, err := listing.ParsedPrice()
+		if err != nil {
+			continue
+		}
+
+		known[ID] = true
+		sold = append(sold, cache.SoldListing{ID: ID, Amount: p.Amount, Currency: p.Currency, SeenAt: now})
+	}
+
+	d.sold[searchURL] = sold
+}
+
+// Evaluate compares the price of the given listing to the sold listings of its search. It returns nil when the price
+// cannot be compared, e.g. when too few listings were sold.
+func (d *Deals) Evaluate(listing scraper.Listing) *scraper.Deal {
+	if d == nil {
+		return nil
+	}
+
+	p, err := listing.ParsedPrice()
+	if err != nil {
+		return nil
+	}
+
+	price, currency := p.Amount, p.Currency
+	if d.rates != nil {
+		converted, ok := d.rates.Convert(price, currency)
+		if !ok {
+			return nil
+		}
+		price, currency = converted, d.rates.Base
+	}
+
+	var prices []float64
+	for _, s := range d.sold[listing.SearchURL] {
+		amount := s.Amount
+		if s.Currency != currency {
+			converted, ok := d.rates.Convert(s.Amount, s.Currency)
+			if !ok {
+				continue
+			}
+			amount = converted
+		}
+		prices = append(prices, amount)
+	}
+
+	if len(prices) < minDealSamples {
+		return nil
+	}
+
+	sort.Float64s(prices)
+	median := percentileValue(prices, 0.5)
+
+	return &scraper.Deal{
+		Percentile: percentileRank(prices, price),
+		Median:     median,
+		Difference: price - median,
+		Currency:   currency,
+		Samples:    len(prices),
+	}
+}
+
+// percentileRank returns the percentage of the given sorted prices which are lower than the given price, the equal
+// prices counting for half.
+func percentileRank(sorted []float64, price float64) float64 {
+	below := sort.SearchFloat64s(sorted, price)
+	equal := 0
+	for i := below; i < len(sorted) && sorted[i] == price; i++ {
+		equal++
+	}
+
+	return 100 * (float64(below) + float64(equal)/2) / float64(len(sorted))
+}
+
+// percentileValue returns the value at the given quantile, between 0 and 1, of the given sorted prices, interpolated
+// between the closest ranks.
+func percentileValue(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
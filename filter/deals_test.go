@@ -0,0 +1,117 @@
+package filter
+
+import (
+	"ebay-watchdog/scraper"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func soldListings(prices ...string) []scraper.Listing {
+	listings := make([]scraper.Listing, len(prices))
+	for i, p := range prices {
+		listings[i] = scraper.Listing{URL: fmt.Sprintf("https://www.ebay.com/itm/%d", 100+i), Price: p}
+	}
+
+	return listings
+}
+
+func TestDealsEvaluate(t *testing.T) {
+	now := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	deals, err := NewDeals(0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deals.now = func() time.Time { return now }
+
+	search := "https://www.ebay.com/sch/i.html?_nkw=duct+tape&_sop=10"
+	deals.Add(search, soldListings("$10.00", "$20.00", "$30.00", "$40.00"))
+
+	listing := scraper.Listing{URL: "https://www.ebay.com/itm/1", Price: "$25.00", SearchURL: search}
+	if d := deals.Evaluate(listing); d != nil {
+		t.Errorf("expected no deal with too few sold listings, got %+v", d)
+	}
+
+	// The known listings are not counted twice.
+	deals.Add(search, soldListings("$10.00", "$20.00", "$30.00", "$40.00", "$50.00", "£5.00"))
+	if n := len(deals.Sold()[search]); n != 6 {
+		t.Fatalf("expected 6 sold listings but got %d", n)
+	}
+
+	d := deals.Evaluate(listing)
+	if d == nil {
+		t.Fatalf("expected a deal")
+	}
+	if d.Samples != 5 || d.Currency != "USD" || d.Median != 30 || d.Difference != -5 || d.Percentile != 40 {
+		t.Errorf("unexpected deal %+v", d)
+	}
+
+	listing.Price = "$30.00"
+	if d := deals.Evaluate(listing); d == nil || d.Percentile != 50 {
+		t.Errorf("expected the equal prices to count for half, got %+v", d)
+	}
+
+	listing.SearchURL = "other"
+	if d := deals.Evaluate(listing); d != nil {
+		t.Errorf("expected no deal for another search, got %+v", d)
+	}
+
+	t.Run("Window", func(t *testing.T) {
+		deals.now = func() time.Time { return now.Add(31 * 24 * time.Hour) }
+		deals.Add(search, soldListings("$60.00"))
+		if n := len(deals.Sold()[search]); n != 1 {
+			t.Errorf("expected the old sold listings to be forgotten, got %d", n)
+		}
+	})
+}
+
+func TestDealsRates(t *testing.T) {
+	rates, err := NewRates("EUR", map[string]float64{"USD": 0.5, "GBP": 1}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deals, err := NewDeals(0, rates)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deals.Add("search", soldListings("$20.00", "$40.00", "£10.00", "£20.00", "£30.00"))
+
+	d := deals.Evaluate(scraper.Listing{URL: "https://www.ebay.co.uk/itm/1", Price: "£15.00", SearchURL: "search"})
+	if d == nil {
+		t.Fatalf("expected a deal")
+	}
+	if d.Currency != "EUR" || d.Median != 20 || d.Difference != -5 || math.Abs(d.Percentile-40) > 1e-9 {
+		t.Errorf("unexpected deal %+v", d)
+	}
+}
+
+func TestMatchPercentile(t *testing.T) {
+	f, err := NewFilter(Rules{MaxPercentile: 30}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		deal *scraper.Deal
+		exp  bool
+	}{
+		{deal: &scraper.Deal{Percentile: 10}, exp: true},
+		{deal: &scraper.Deal{Percentile: 30}, exp: true},
+		{deal: &scraper.Deal{Percentile: 45}, exp: false},
+		{deal: nil, exp: true},
+	}
+
+	for _, tt := range tests {
+		got, reason := f.Match(scraper.Listing{Title: "Duct tape", Deal: tt.deal})
+		if got != tt.exp {
+			t.Errorf("expected %v but got %v for %+v (%s)", tt.exp, got, tt.deal, reason)
+		}
+	}
+
+	if _, err := NewFilter(Rules{MaxPercentile: 120}, nil); err == nil {
+		t.Errorf("expected an error for an invalid percentile")
+	}
+}
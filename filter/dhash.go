@@ -0,0 +1,77 @@
+package filter
+
+import (
+	"bytes"
+	"ebay-watchdog/web"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+)
+
+// maxImageDistance is the maximum number of different bits between the hashes of two similar images.
+const maxImageDistance = 10
+
+// hashImageURL returns the perceptual hash of the image at the given URL, fetched with the given client.
+func hashImageURL(client *web.Client, URL string) (uint64, error) {
+	body, err := client.GetBody(URL)
+	if err != nil {
+		return 0, fmt.Errorf("could not get image %s: %v", URL, err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("could not decode image %s: %v", URL, err)
+	}
+
+	return dHash(img), nil
+}
+
+// dHash returns the difference hash of the given image: the image is reduced to 9x8 gray cells, and each bit tells
+// whether a cell is brighter than the next one on its row. Similar images have hashes with few different bits.
+func dHash(img image.Image) uint64 {
+	const w, h = 9, 8
+
+	b := img.Bounds()
+	var cells [h][w]float64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+			if x1 == x0 {
+				x1++
+			}
+			if y1 == y0 {
+				y1++
+			}
+
+			var sum float64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					r, g, bl, _ := img.At(px, py).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+				}
+			}
+			cells[y][x] = sum / float64((x1-x0)*(y1-y0))
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+// imageDistance returns the number of different bits between the given hashes.
+func imageDistance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
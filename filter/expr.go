@@ -0,0 +1,469 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// kind is the type of an expression.
+type kind int
+
+const (
+	kindBool kind = iota
+	kindNumber
+	kindString
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindBool:
+		return "boolean"
+	case kindNumber:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// value is the result of the evaluation of an expression.
+type value struct {
+	b bool
+	n float64
+	s string
+	// currency is set for the numbers which are amounts of money, e.g. 200 EUR.
+	currency string
+	// missing is true when the listing has no such data, e.g. a price which could not be parsed. Any comparison with
+	// a missing value is false.
+	missing bool
+}
+
+// node is a compiled and type checked expression.
+type node struct {
+	kind kind
+	eval func(env *env) value
+}
+
+// Expr is a compiled boolean expression on a listing, e.g.
+// price < 200 EUR and (free_shipping or location contains 'Germany') and title !~ 'broken'.
+type Expr struct {
+	src  string
+	root node
+	// currencies are the currency codes of the amounts of money of the expression.
+	currencies []token
+}
+
+// CompileExpr parses and type checks the given expression. The errors give the column of the problem.
+func CompileExpr(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, errorAt(t, "unexpected %s", t)
+	}
+
+	if root.kind != kindBool {
+		return nil, fmt.Errorf("the expression is a %s, not a boolean", root.kind)
+	}
+
+	return &Expr{src: src, root: root, currencies: p.currencies}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// eval returns whether the expression is true in the given environment.
+func (e *Expr) eval(env *env) bool {
+	return e.root.eval(env).b
+}
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) String() string {
+	if t.typ == tokenEOF {
+		return "end of expression"
+	}
+
+	return fmt.Sprintf("%q", t.val)
+}
+
+// errorAt returns an error located at the given token.
+func errorAt(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("column %d: %s", t.pos+1, fmt.Sprintf(format, args...))
+}
+
+// operators are the symbolic operators, longest first.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "!~", "<", ">", "!", "~"}
+
+// tokenize splits the given expression into tokens.
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{typ: tokenLParen, val: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{typ: tokenRParen, val: ")", pos: i})
+			i++
+		case r == '\'' || r == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == r {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("column %d: unterminated string", start+1)
+			}
+			i++
+			tokens = append(tokens, token{typ: tokenString, val: b.String(), pos: start})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{typ: tokenNumber, val: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{typ: tokenIdent, val: string(runes[start:i]), pos: start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, token{typ: tokenOperator, val: op, pos: i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("column %d: unexpected character %q", i+1, r)
+			}
+		}
+	}
+
+	return append(tokens, token{typ: tokenEOF, pos: len(runes)}), nil
+}
+
+// parser is a recursive descent parser of the expressions:
+//
+//	or      = and { ("or" | "||") and }
+//	and     = not { ("and" | "&&") not }
+//	not     = ("not" | "!") not | compare
+//	compare = primary [ ("==" | "!=" | "<" | "<=" | ">" | ">=" | "contains" | "~" | "!~") primary ]
+//	primary = number [ currency ] | string | "true" | "false" | field | "(" or ")"
+type parser struct {
+	tokens     []token
+	pos        int
+	currencies []token
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given keywords or operators.
+func (p *parser) accept(vals ...string) (token, bool) {
+	t := p.peek()
+	if t.typ != tokenIdent && t.typ != tokenOperator {
+		return t, false
+	}
+
+	for _, v := range vals {
+		if t.val == v {
+			return p.next(), true
+		}
+	}
+
+	return t, false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return node{}, err
+	}
+
+	for {
+		op, ok := p.accept("or", "||")
+		if !ok {
+			return left, nil
+		}
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return node{}, err
+		}
+
+		if err := expectKinds(op, kindBool, left, right); err != nil {
+			return node{}, err
+		}
+
+		l, r := left, right
+		left = node{kind: kindBool, eval: func(env *env) value {
+			return value{b: l.eval(env).b || r.eval(env).b}
+		}}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return node{}, err
+	}
+
+	for {
+		op, ok := p.accept("and", "&&")
+		if !ok {
+			return left, nil
+		}
+
+		right, err := p.parseNot()
+		if err != nil {
+			return node{}, err
+		}
+
+		if err := expectKinds(op, kindBool, left, right); err != nil {
+			return node{}, err
+		}
+
+		l, r := left, right
+		left = node{kind: kindBool, eval: func(env *env) value {
+			return value{b: l.eval(env).b && r.eval(env).b}
+		}}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	op, ok := p.accept("not", "!")
+	if !ok {
+		return p.parseCompare()
+	}
+
+	operand, err := p.parseNot()
+	if err != nil {
+		return node{}, err
+	}
+
+	if err := expectKinds(op, kindBool, operand); err != nil {
+		return node{}, err
+	}
+
+	return node{kind: kindBool, eval: func(env *env) value {
+		return value{b: !operand.eval(env).b}
+	}}, nil
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return node{}, err
+	}
+
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "contains", "~", "!~")
+	if !ok {
+		return left, nil
+	}
+
+	rightToken := p.peek()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return node{}, err
+	}
+
+	switch op.val {
+	case "==", "!=":
+		if left.kind != right.kind {
+			return node{}, errorAt(op, "cannot compare a %s with a %s", left.kind, right.kind)
+		}
+		negate := op.val == "!="
+		return node{kind: kindBool, eval: func(env *env) value {
+			l, r := left.eval(env), right.eval(env)
+			if l.missing || r.missing {
+				return value{}
+			}
+			if left.kind == kindNumber {
+				ln, rn, ok := env.sameCurrency(l, r)
+				if !ok {
+					return value{}
+				}
+				return value{b: (ln == rn) != negate}
+			}
+			if left.kind == kindString {
+				return value{b: strings.EqualFold(l.s, r.s) != negate}
+			}
+			return value{b: (l.b == r.b) != negate}
+		}}, nil
+	case "<", "<=", ">", ">=":
+		if err := expectKinds(op, kindNumber, left, right); err != nil {
+			return node{}, err
+		}
+		cmp := op.val
+		return node{kind: kindBool, eval: func(env *env) value {
+			l, r := left.eval(env), right.eval(env)
+			if l.missing || r.missing {
+				return value{}
+			}
+			ln, rn, ok := env.sameCurrency(l, r)
+			if !ok {
+				return value{}
+			}
+			switch cmp {
+			case "<":
+				return value{b: ln < rn}
+			case "<=":
+				return value{b: ln <= rn}
+			case ">":
+				return value{b: ln > rn}
+			default:
+				return value{b: ln >= rn}
+			}
+		}}, nil
+	case "contains":
+		if err := expectKinds(op, kindString, left, right); err != nil {
+			return node{}, err
+		}
+		return node{kind: kindBool, eval: func(env *env) value {
+			l, r := left.eval(env), right.eval(env)
+			if l.missing || r.missing {
+				return value{}
+			}
+			return value{b: strings.Contains(strings.ToLower(l.s), strings.ToLower(r.s))}
+		}}, nil
+	default:
+		if err := expectKinds(op, kindString, left); err != nil {
+			return node{}, err
+		}
+		if rightToken.typ != tokenString {
+			return node{}, errorAt(rightToken, "the right operand of %s must be a quoted regular expression", op.val)
+		}
+		re, err := regexp.Compile("(?i)" + rightToken.val)
+		if err != nil {
+			return node{}, errorAt(rightToken, "invalid regular expression: %v", err)
+		}
+		negate := op.val == "!~"
+		return node{kind: kindBool, eval: func(env *env) value {
+			l := left.eval(env)
+			if l.missing {
+				return value{}
+			}
+			return value{b: re.MatchString(l.s) != negate}
+		}}, nil
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.typ {
+	case tokenNumber:
+		n, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return node{}, errorAt(t, "invalid number %s", t.val)
+		}
+		v := value{n: n}
+		// An amount of money, e.g. 200 EUR.
+		if c := p.peek(); c.typ == tokenIdent && isCurrencyCode(c.val) {
+			v.currency = p.next().val
+			p.currencies = append(p.currencies, c)
+		}
+		return node{kind: kindNumber, eval: func(*env) value { return v }}, nil
+	case tokenString:
+		v := value{s: t.val}
+		return node{kind: kindString, eval: func(*env) value { return v }}, nil
+	case tokenIdent:
+		switch t.val {
+		case "true", "false":
+			v := value{b: t.val == "true"}
+			return node{kind: kindBool, eval: func(*env) value { return v }}, nil
+		}
+		f, ok := fields[t.val]
+		if !ok {
+			return node{}, errorAt(t, "unknown field %s, expected one of %s", t.val, strings.Join(fieldNames(), ", "))
+		}
+		return node{kind: f.kind, eval: f.get}, nil
+	case tokenLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return node{}, err
+		}
+		if c := p.next(); c.typ != tokenRParen {
+			return node{}, errorAt(c, "expected \")\" but got %s", c)
+		}
+		return n, nil
+	default:
+		return node{}, errorAt(t, "unexpected %s", t)
+	}
+}
+
+// expectKinds returns an error if one of the given operands is not of the given kind.
+func expectKinds(op token, k kind, operands ...node) error {
+	for _, o := range operands {
+		if o.kind != k {
+			return errorAt(op, "%s expects %s operands but got a %s", op.val, k, o.kind)
+		}
+	}
+
+	return nil
+}
+
+// isCurrencyCode returns whether the given identifier is an ISO 4217 currency code, e.g. EUR.
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,275 @@
+package filter
+
+import (
+	"ebay-watchdog/scraper"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Rules are the conditions a listing must meet to be notified. Empty rules keep every listing.
+type Rules struct {
+	// Include are keywords which must all appear in the title or subtitle.
+	Include []string
+	// Exclude are keywords which must not appear in the title or subtitle.
+	Exclude []string
+	// Regex are regular expressions which must all match the title or subtitle.
+	Regex []string
+	// ExcludeRegex are regular expressions which must not match the title or subtitle.
+	ExcludeRegex []string
+	// Condition are the accepted conditions. The listings without condition are kept.
+	Condition []string
+	// MinPrice and MaxPrice bound the price, converted into the base currency. 0 means no bound. The listings whose
+	// price cannot be converted are kept.
+	MinPrice float64
+	MaxPrice float64
+	// MaxPercentile is the maximum percentile of the price among the recently sold listings of the search, from 0 to
+	// 100. 0 means no bound. The listings whose price cannot be compared are kept.
+	MaxPercentile float64
+}
+
+// Filter decides which listings are notified. The keywords, regular expressions and conditions are case insensitive.
+type Filter struct {
+	include       []string
+	exclude       []string
+	regex         []*regexp.Regexp
+	excludeRegex  []*regexp.Regexp
+	condition     []string
+	minPrice      float64
+	maxPrice      float64
+	maxPercentile float64
+	rates         *Rates
+}
+
+// NewFilter compiles the given rules. The prices are converted with the given rates, which are needed when the rules
+// bound the price. It returns an error if a regular expression is invalid.
+func NewFilter(rules Rules, rates *Rates) (*Filter, error) {
+	if (rules.MinPrice != 0 || rules.MaxPrice != 0) && rates == nil {
+		return nil, fmt.Errorf("price bounds need a base currency")
+	}
+
+	if rules.MinPrice < 0 || rules.MaxPrice < 0 || (rules.MaxPrice > 0 && rules.MinPrice > rules.MaxPrice) {
+		return nil, fmt.Errorf("invalid price range %v-%v", rules.MinPrice, rules.MaxPrice)
+	}
+
+	if rules.MaxPercentile < 0 || rules.MaxPercentile > 100 {
+		return nil, fmt.Errorf("invalid max percentile %v, expected a number between 0 and 100", rules.MaxPercentile)
+	}
+
+	regex, err := compile(rules.Regex)
+	if err != nil {
+		return nil, err
+	}
+
+	excludeRegex, err := compile(rules.ExcludeRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Filter{
+		include:       lower(rules.Include),
+		exclude:       lower(rules.Exclude),
+		regex:         regex,
+		excludeRegex:  excludeRegex,
+		condition:     lower(rules.Condition),
+		minPrice:      rules.MinPrice,
+		maxPrice:      rules.MaxPrice,
+		maxPercentile: rules.MaxPercentile,
+		rates:         rates,
+	}, nil
+}
+
+// Match returns whether the given listing meets the rules. When it does not, it also returns the reason.
+func (f *Filter) Match(listing scraper.Listing) (bool, string) {
+	if f == nil {
+		return true, ""
+	}
+
+	text := strings.ToLower(listing.Title + "\n" + listing.Subtitle)
+
+	for _, k := range f.include {
+		if !strings.Contains(text, k) {
+			return false, fmt.Sprintf("missing keyword %q", k)
+		}
+	}
+
+	for _, k := range f.exclude {
+		if strings.Contains(text, k) {
+			return false, fmt.Sprintf("excluded keyword %q", k)
+		}
+	}
+
+	for _, r := range f.regex {
+		if !r.MatchString(text) {
+			return false, fmt.Sprintf("no match for %s", r)
+		}
+	}
+
+	for _, r := range f.excludeRegex {
+		if r.MatchString(text) {
+			return false, fmt.Sprintf("excluded match for %s", r)
+		}
+	}
+
+	if len(f.condition) > 0 && listing.Condition != "" && !matchesAny(strings.ToLower(listing.Condition), f.condition) {
+		return false, fmt.Sprintf("condition %q", listing.Condition)
+	}
+
+	if f.maxPercentile > 0 && listing.Deal != nil && listing.Deal.Percentile > f.maxPercentile {
+		return false, fmt.Sprintf("price in the %.0fth percentile of the sold listings", listing.Deal.Percentile)
+	}
+
+	if f.minPrice == 0 && f.maxPrice == 0 {
+		return true, ""
+	}
+
+	price, ok := convertedPrice(listing, f.rates)
+	if !ok {
+		log.Printf("could not convert the price %s of listing %s into %s, keeping it\n", listing.Price, listing.ID, f.rates.Base)
+		return true, ""
+	}
+
+	if price < f.minPrice || (f.maxPrice > 0 && price > f.maxPrice) {
+		return false, fmt.Sprintf("price %.2f %s out of range", price, f.rates.Base)
+	}
+
+	return true, ""
+}
+
+// convertedPrice returns the price of the given listing converted with the given rates.
+func convertedPrice(listing scraper.Listing, rates *Rates) (float64, bool) {
+	p, err := listing.ParsedPrice()
+	if err != nil {
+		return 0, false
+	}
+
+	return rates.Convert(p.Amount, p.Currency)
+}
+
+// matchesAny returns whether the given text contains one of the given words.
+func matchesAny(text string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(text, w) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compile compiles the given case insensitive regular expressions.
+func compile(exprs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(exprs))
+	for _, e := range exprs {
+		r, err := regexp.Compile("(?i)" + e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %s: %v", e, err)
+		}
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// lower returns the given words in lower case, without the empty ones.
+func lower(words []string) []string {
+	res := make([]string, 0, len(words))
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w != "" {
+			res = append(res, w)
+		}
+	}
+
+	return res
+}
+
+// Set is the global filter, and the filters and rules of each search.
+type Set struct {
+	Global *Filter
+	// Searches are the filters of each search, by search URL.
+	Searches map[string]*Filter
+	// Rules are the rules engines of each search, by search URL.
+	Rules map[string]*Engine
+	// Rates, when set, are used to show the prices converted into the base currency.
+	Rates *Rates
+	// Sellers are the seller allow and deny lists.
+	Sellers *Sellers
+	// Deals compares the prices to the recently sold listings. It is nil when the comparison is disabled.
+	Deals *Deals
+	// Relists detects the relisted listings. It is nil when the detection is disabled.
+	Relists *Relists
+}
+
+// Apply returns the given listings which meet both the global filter and the filter of their search.
+func (s Set) Apply(listings []scraper.Listing) []scraper.Listing {
+	if err := s.Rates.Refresh(); err != nil {
+		log.Println("could not refresh the exchange rates, keeping the previous ones", err)
+	}
+
+	var res []scraper.Listing
+	for _, listing := range listings {
+		listing.Deal = s.Deals.Evaluate(listing)
+
+		ok, reason := s.Global.Match(listing)
+		if ok {
+			ok, reason = s.Searches[listing.SearchURL].Match(listing)
+		}
+
+		if !ok {
+			log.Printf("Filtered out listing %s (%s): %s\n", listing.ID, listing.Title, reason)
+			continue
+		}
+
+		if p, err := listing.ParsedPrice(); err == nil && s.Rates != nil && p.Currency != s.Rates.Base {
+			if converted, ok := s.Rates.Convert(p.Amount, p.Currency); ok {
+				listing.ConvertedPrice = fmt.Sprintf("%.2f %s", converted, s.Rates.Base)
+			}
+		}
+
+		res = append(res, listing)
+	}
+
+	return res
+}
+
+// ApplyRules returns the given listings which are not dropped by the rules of their search, with the decisions of
+// the rules set. As the rules can use the seller and its feedback, they are applied once the listings are enriched.
+func (s Set) ApplyRules(listings []scraper.Listing) []scraper.Listing {
+	var res []scraper.Listing
+	for _, listing := range listings {
+		decision := s.Rules[listing.SearchURL].Decide(listing)
+		if decision.Drop {
+			log.Printf("Dropped listing %s (%s) by rule\n", listing.ID, listing.Title)
+			continue
+		}
+
+		listing.Silent = decision.Silent
+		listing.Tags = append(listing.Tags, decision.Tags...)
+		res = append(res, listing)
+	}
+
+	return res
+}
+
+// ApplySellers returns the given listings which are not excluded by the seller lists. As the seller may only be known
+// once the listings are enriched, it is applied separately, right before the notifications.
+func (s Set) ApplySellers(listings []scraper.Listing) []scraper.Listing {
+	if err := s.Sellers.Refresh(); err != nil {
+		log.Println("could not refresh the seller lists, keeping the previous ones", err)
+	}
+
+	var res []scraper.Listing
+	for _, listing := range listings {
+		ok, reason := s.Sellers.Match(listing.Seller, listing.SearchURL)
+		if !ok {
+			log.Printf("Filtered out listing %s (%s): %s\n", listing.ID, listing.Title, reason)
+			continue
+		}
+
+		res = append(res, listing)
+	}
+
+	return res
+}
@@ -0,0 +1,90 @@
+package filter
+
+import (
+	"ebay-watchdog/scraper"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	f, err := NewFilter(Rules{
+		Include:      []string{"Nintendo"},
+		Exclude:      []string{"for parts", "box only"},
+		Regex:        []string{`\b(ds|3ds)\b`},
+		ExcludeRegex: []string{`repli(ca|que)`},
+		Condition:    []string{"new", "used"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		listing scraper.Listing
+		exp     bool
+	}{
+		{listing: scraper.Listing{Title: "NINTENDO 3DS XL blue", Condition: "Used"}, exp: true},
+		{listing: scraper.Listing{Title: "Nintendo DS", Subtitle: "Box only, no console"}, exp: false},
+		{listing: scraper.Listing{Title: "Nintendo DS lite", Condition: "For parts or not working"}, exp: false},
+		{listing: scraper.Listing{Title: "Nintendo DS replica shell"}, exp: false},
+		{listing: scraper.Listing{Title: "Nintendo Switch"}, exp: false},
+		{listing: scraper.Listing{Title: "Sony DS"}, exp: false},
+		{listing: scraper.Listing{Title: "Nintendo DS, no condition"}, exp: true},
+	}
+
+	for _, tt := range tests {
+		got, reason := f.Match(tt.listing)
+		if got != tt.exp {
+			t.Errorf("expected %v but got %v for %+v (%s)", tt.exp, got, tt.listing, reason)
+		}
+	}
+}
+
+func TestNewFilterInvalidRegex(t *testing.T) {
+	if _, err := NewFilter(Rules{Regex: []string{"(ds"}}, nil); err == nil {
+		t.Errorf("expected an error for an invalid regex")
+	}
+}
+
+func TestSetApply(t *testing.T) {
+	global, _ := NewFilter(Rules{Exclude: []string{"broken"}}, nil)
+	search, _ := NewFilter(Rules{Include: []string{"blue"}}, nil)
+
+	set := Set{
+		Global:   global,
+		Searches: map[string]*Filter{"search-1": search},
+	}
+
+	listings := []scraper.Listing{
+		{ID: "1", Title: "Blue tape", SearchURL: "search-1"},
+		{ID: "2", Title: "Red tape", SearchURL: "search-1"},
+		{ID: "3", Title: "Red tape", SearchURL: "search-2"},
+		{ID: "4", Title: "Broken blue tape", SearchURL: "search-1"},
+	}
+
+	got := set.Apply(listings)
+	if len(got) != 2 || got[0].ID != "1" || got[1].ID != "3" {
+		t.Errorf("unexpected listings %+v", got)
+	}
+}
+
+func TestSetApplyRules(t *testing.T) {
+	engine, err := NewEngine([]Rule{
+		{When: `feedback_score < 50`, Action: ActionDrop},
+		{When: `title contains 'rare'`, Action: ActionSilent},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	set := Set{Rules: map[string]*Engine{"search-1": engine}}
+
+	listings := []scraper.Listing{
+		{ID: "1", Title: "Rare tape", SearchURL: "search-1"},
+		{ID: "2", Title: "Tape", SearchURL: "search-1", Enriched: true, Seller: "new_seller", SellerFeedbackScore: 3},
+		{ID: "3", Title: "Tape", SearchURL: "search-2", Enriched: true, Seller: "new_seller"},
+	}
+
+	got := set.ApplyRules(listings)
+	if len(got) != 2 || got[0].ID != "1" || !got[0].Silent || got[1].ID != "3" {
+		t.Errorf("unexpected listings %+v", got)
+	}
+}
@@ -0,0 +1,247 @@
+package filter
+
+import (
+	"ebay-watchdog/cache"
+	"ebay-watchdog/scraper"
+	"ebay-watchdog/web"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// RelistSuppress does not notify the relisted listings.
+	RelistSuppress = "suppress"
+	// RelistAnnotate notifies the relisted listings, with the relisted tag.
+	RelistAnnotate = "annotate"
+)
+
+// Relists detects the listings which were ended and listed again under a new item ID, by comparing the new listings to
+// the recently notified ones: a relisted listing has a similar normalised title, the same price, the same seller when
+// both are known, and a similar thumbnail when the images are compared.
+type Relists struct {
+	Action string
+	// Window is the period during which the notified listings are remembered.
+	Window time.Duration
+	// Similarity is the minimum similarity of the titles, between 0 and 1.
+	Similarity float64
+
+	recent    []cache.NotifiedListing
+	hashImage func(URL string) (uint64, error)
+	// blocked returns whether the site of the given listing URL blocked a request.
+	blocked func(URL string) bool
+	now     func() time.Time
+}
+
+// NewRelists returns a relist detector with the given action, window and title similarity. When an images client is
+// given, the thumbnail of each listing is downloaded with it and hashed.
+func NewRelists(action string, window time.Duration, similarity float64, images *web.Client) (*Relists, error) {
+	if action == "" {
+		action = RelistAnnotate
+	}
+	if action != RelistSuppress && action != RelistAnnotate {
+		return nil, fmt.Errorf("unknown action %q, expected suppress or annotate", action)
+	}
+
+	if window == 0 {
+		window = 72 * time.Hour
+	}
+	if window < 0 {
+		return nil, fmt.Errorf("invalid window %v", window)
+	}
+
+	if similarity == 0 {
+		similarity = 0.8
+	}
+	if similarity < 0 || similarity > 1 {
+		return nil, fmt.Errorf("invalid similarity %v, expected a number between 0 and 1", similarity)
+	}
+
+	r := &Relists{
+		Action:     action,
+		Window:     window,
+		Similarity: similarity,
+		now:        time.Now,
+	}
+	if images != nil {
+		r.hashImage = func(URL string) (uint64, error) {
+			return hashImageURL(images, URL)
+		}
+		r.blocked = func(URL string) bool {
+			return images.Blocked(URL) != nil
+		}
+	}
+
+	return r, nil
+}
+
+// Load sets the recently notified listings, e.g. from the cache.
+func (r *Relists) Load(notified []cache.NotifiedListing) {
+	if r == nil {
+		return
+	}
+
+	r.recent = notified
+}
+
+// Notified returns the recently notified listings, to be persisted.
+func (r *Relists) Notified() []cache.NotifiedListing {
+	if r == nil {
+		return nil
+	}
+
+	return r.recent
+}
+
+// Apply returns the given listings without the relisted ones, or with them tagged, depending on the action. All the
+// given listings are remembered, so that the later relists are detected as well.
+func (r *Relists) Apply(listings []scraper.Listing) []scraper.Listing {
+	if r == nil {
+		return listings
+	}
+
+	now := r.now()
+	r.prune(now)
+
+	var res []scraper.Listing
+	for _, listing := range listings {
+		notified := r.notifiedListing(listing, now)
+		original, found := r.find(notified)
+		r.recent = append(r.recent, notified)
+
+		if !found {
+			res = append(res, listing)
+			continue
+		}
+
+		if r.Action == RelistSuppress {
+			log.Printf("Suppressed listing %s (%s): relist of %s\n", listing.ID, listing.Title, original.ID)
+			continue
+		}
+
+		listing.RelistOf = original.URL
+		listing.Tags = append(listing.Tags, "relisted")
+		res = append(res, listing)
+	}
+
+	return res
+}
+
+// prune forgets the listings notified before the window.
+func (r *Relists) prune(now time.Time) {
+	var recent []cache.NotifiedListing
+	for _, n := range r.recent {
+		if now.Sub(n.NotifiedAt) <= r.Window {
+			recent = append(recent, n)
+		}
+	}
+
+	r.recent = recent
+}
+
+// notifiedListing returns the given listing as it is remembered, with the hash of its thumbnail when the images are
+// compared. The thumbnail is not downloaded while the site of the listing blocks the requests.
+func (r *Relists) notifiedListing(listing scraper.Listing, now time.Time) cache.NotifiedListing {
+	n := cache.NotifiedListing{
+		ID:         listing.ID,
+		URL:        listing.URL,
+		Title:      listing.Title,
+		Seller:     listing.Seller,
+		Price:      listing.Price,
+		NotifiedAt: now,
+	}
+
+	if r.hashImage != nil && listing.Image != "" && !r.blocked(listing.URL) {
+		hash, err := r.hashImage(listing.Image)
+		if err != nil {
+			log.Println("could not hash the thumbnail, ignoring it", err)
+		} else {
+			n.ImageHash = hash
+		}
+	}
+
+	return n
+}
+
+// find returns the most recent notified listing of which the given one is a relist.
+func (r *Relists) find(n cache.NotifiedListing) (cache.NotifiedListing, bool) {
+	for i := len(r.recent) - 1; i >= 0; i-- {
+		o := r.recent[i]
+		if o.ID == n.ID {
+			continue
+		}
+
+		knownSellers := n.Seller != "" && o.Seller != ""
+		if knownSellers && !strings.EqualFold(n.Seller, o.Seller) {
+			continue
+		}
+
+		if !samePrice(n, o) {
+			continue
+		}
+
+		// Without the sellers, only a similar thumbnail tells that the listings come from the same seller.
+		comparedImages := n.ImageHash != 0 && o.ImageHash != 0
+		if !knownSellers && !comparedImages {
+			continue
+		}
+
+		if comparedImages && imageDistance(n.ImageHash, o.ImageHash) > maxImageDistance {
+			continue
+		}
+
+		if titleSimilarity(n.Title, o.Title) < r.Similarity {
+			continue
+		}
+
+		return o, true
+	}
+
+	return cache.NotifiedListing{}, false
+}
+
+// samePrice returns whether the given listings have the same price. The prices which cannot be parsed are compared
+// as they are displayed.
+func samePrice(a cache.NotifiedListing, b cache.NotifiedListing) bool {
+	pa, errA := scraper.Listing{URL: a.URL, Price: a.Price}.ParsedPrice()
+	pb, errB := scraper.Listing{URL: b.URL, Price: b.Price}.ParsedPrice()
+	if errA != nil || errB != nil {
+		return strings.TrimSpace(a.Price) == strings.TrimSpace(b.Price)
+	}
+
+	return pa.Currency == pb.Currency && pa.Amount == pb.Amount
+}
+
+// titleSimilarity returns the similarity of the words of the given titles, between 0 and 1, once normalised: case,
+// punctuation and word order are ignored.
+func titleSimilarity(a string, b string) float64 {
+	wa, wb := titleWords(a), titleWords(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+
+	common := 0
+	for w := range wa {
+		if wb[w] {
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(wa)+len(wb))
+}
+
+// titleWords returns the set of the lowercased words of the given title.
+func titleWords(title string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	res := make(map[string]bool, len(words))
+	for _, w := range words {
+		res[w] = true
+	}
+
+	return res
+}
@@ -0,0 +1,239 @@
+package filter
+
+import (
+	"ebay-watchdog/cache"
+	"ebay-watchdog/scraper"
+	"ebay-watchdog/web"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestRelistsApply(t *testing.T) {
+	now := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	original := cache.NotifiedListing{
+		ID:         "1",
+		URL:        "https://www.ebay.com/itm/1",
+		Title:      "Nintendo 3DS XL - Blue, with charger",
+		Seller:     "game_shop",
+		Price:      "$120.00",
+		NotifiedAt: now.Add(-time.Hour),
+	}
+
+	tests := []struct {
+		name    string
+		listing scraper.Listing
+		exp     bool
+	}{
+		{
+			name:    "Relist",
+			listing: scraper.Listing{ID: "2", URL: "https://www.ebay.com/itm/2", Title: "nintendo 3ds xl blue with charger", Seller: "Game_Shop", Price: "$120.00"},
+			exp:     true,
+		},
+		{
+			name:    "Unknown seller without thumbnails",
+			listing: scraper.Listing{ID: "2", URL: "https://www.ebay.com/itm/2", Title: "Nintendo 3DS XL Blue with charger", Price: "$120.00"},
+			exp:     false,
+		},
+		{
+			name:    "Other seller",
+			listing: scraper.Listing{ID: "2", URL: "https://www.ebay.com/itm/2", Title: "Nintendo 3DS XL Blue with charger", Seller: "other", Price: "$120.00"},
+			exp:     false,
+		},
+		{
+			name:    "Other price",
+			listing: scraper.Listing{ID: "2", URL: "https://www.ebay.com/itm/2", Title: "Nintendo 3DS XL Blue with charger", Seller: "game_shop", Price: "$110.00"},
+			exp:     false,
+		},
+		{
+			name:    "Other title",
+			listing: scraper.Listing{ID: "2", URL: "https://www.ebay.com/itm/2", Title: "Nintendo 2DS red", Seller: "game_shop", Price: "$120.00"},
+			exp:     false,
+		},
+		{
+			name:    "Same item",
+			listing: scraper.Listing{ID: "1", URL: "https://www.ebay.com/itm/1", Title: "Nintendo 3DS XL - Blue, with charger", Seller: "game_shop", Price: "$120.00"},
+			exp:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRelists(RelistAnnotate, 0, 0, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r.now = func() time.Time { return now }
+			r.Load([]cache.NotifiedListing{original})
+
+			res := r.Apply([]scraper.Listing{tt.listing})
+			if len(res) != 1 {
+				t.Fatalf("expected the listing to be notified, got %+v", res)
+			}
+
+			if got := res[0].RelistOf != ""; got != tt.exp {
+				t.Errorf("expected relist %v but got %v (%+v)", tt.exp, got, res[0])
+			}
+			if tt.exp && (res[0].RelistOf != original.URL || len(res[0].Tags) != 1 || res[0].Tags[0] != "relisted") {
+				t.Errorf("unexpected annotation %+v", res[0])
+			}
+
+			if len(r.Notified()) != 2 {
+				t.Errorf("expected the listing to be remembered, got %+v", r.Notified())
+			}
+		})
+	}
+
+	t.Run("Suppress", func(t *testing.T) {
+		r, err := NewRelists(RelistSuppress, 0, 0, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r.now = func() time.Time { return now }
+
+		listings := []scraper.Listing{
+			{ID: "1", URL: "https://www.ebay.com/itm/1", Title: "Nintendo 3DS XL blue", Price: "$120.00", Seller: "game_shop"},
+			{ID: "2", URL: "https://www.ebay.com/itm/2", Title: "Nintendo 3DS XL blue", Price: "$120.00", Seller: "game_shop"},
+		}
+		if res := r.Apply(listings); len(res) != 1 || res[0].ID != "1" {
+			t.Errorf("expected the relist in the same batch to be suppressed, got %+v", res)
+		}
+	})
+
+	t.Run("Window", func(t *testing.T) {
+		r, err := NewRelists(RelistSuppress, 24*time.Hour, 0, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r.now = func() time.Time { return now.Add(48 * time.Hour) }
+		r.Load([]cache.NotifiedListing{original})
+
+		listing := scraper.Listing{ID: "2", URL: "https://www.ebay.com/itm/2", Title: original.Title, Price: original.Price, Seller: original.Seller}
+		if res := r.Apply([]scraper.Listing{listing}); len(res) != 1 {
+			t.Errorf("expected the listings notified before the window to be forgotten")
+		}
+		if n := r.Notified(); len(n) != 1 || n[0].ID != "2" {
+			t.Errorf("unexpected notified listings %+v", n)
+		}
+	})
+
+	t.Run("Unknown sellers", func(t *testing.T) {
+		r, err := NewRelists(RelistSuppress, 0, 0, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r.now = func() time.Time { return now }
+		r.Load([]cache.NotifiedListing{original})
+
+		// Without enrichment, the search results usually do not give the seller.
+		listings := []scraper.Listing{
+			{ID: "2", URL: "https://www.ebay.com/itm/2", Title: original.Title, Price: original.Price},
+			{ID: "3", URL: "https://www.ebay.com/itm/3", Title: "Nintendo 3DS XL blue", Price: "$90.00"},
+			{ID: "4", URL: "https://www.ebay.com/itm/4", Title: "Nintendo 3DS XL blue", Price: "$90.00", Seller: "other"},
+		}
+		if res := r.Apply(listings); len(res) != 3 {
+			t.Errorf("expected the listings without both sellers nor thumbnails not to be relists, got %+v", res)
+		}
+	})
+
+	t.Run("Images", func(t *testing.T) {
+		r, err := NewRelists(RelistSuppress, 0, 0, web.DefaultClient)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r.now = func() time.Time { return now }
+		hashes := map[string]uint64{"a.jpg": 0xF0F0F0F0F0F0F0F0, "b.jpg": 0xF0F0F0F0F0F0F0F1, "c.jpg": 0x0F0F0F0F0F0F0F0F}
+		r.hashImage = func(URL string) (uint64, error) {
+			if h, ok := hashes[URL]; ok {
+				return h, nil
+			}
+			return 0, fmt.Errorf("not found")
+		}
+
+		listings := []scraper.Listing{
+			{ID: "1", URL: "https://www.ebay.com/itm/1", Title: "Nintendo 3DS XL blue", Price: "$120.00", Image: "a.jpg"},
+			{ID: "2", URL: "https://www.ebay.com/itm/2", Title: "Nintendo 3DS XL blue", Price: "$120.00", Image: "c.jpg"},
+			{ID: "3", URL: "https://www.ebay.com/itm/3", Title: "Nintendo 3DS XL blue", Price: "$120.00", Image: "b.jpg"},
+		}
+		res := r.Apply(listings)
+		if len(res) != 2 || res[0].ID != "1" || res[1].ID != "2" {
+			t.Errorf("expected only the listing with a similar thumbnail to be suppressed, got %+v", res)
+		}
+	})
+
+	t.Run("Blocked site", func(t *testing.T) {
+		client := web.NewClient(web.ClientOptions{})
+		client.Block("https://www.ebay.com/sch/i.html?_nkw=3ds", &web.BlockedError{StatusCode: 429})
+
+		r, err := NewRelists(RelistSuppress, 0, 0, client)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r.now = func() time.Time { return now }
+		hashed := 0
+		r.hashImage = func(URL string) (uint64, error) {
+			hashed++
+			return 0xF0F0F0F0F0F0F0F0, nil
+		}
+
+		listings := []scraper.Listing{
+			{ID: "1", URL: "https://www.ebay.com/itm/1", Title: "Nintendo 3DS XL blue", Price: "$120.00", Image: "a.jpg"},
+			{ID: "2", URL: "https://www.ebay.com/itm/2", Title: "Nintendo 3DS XL blue", Price: "$120.00", Image: "b.jpg"},
+		}
+		if res := r.Apply(listings); len(res) != 2 {
+			t.Errorf("expected the listings without compared thumbnails not to be relists, got %+v", res)
+		}
+		if hashed != 0 {
+			t.Errorf("expected the thumbnails of the blocked site not to be downloaded, got %d", hashed)
+		}
+	})
+}
+
+func TestNewRelists(t *testing.T) {
+	if _, err := NewRelists("ignore", 0, 0, nil); err == nil {
+		t.Errorf("expected an error for an unknown action")
+	}
+
+	if _, err := NewRelists(RelistAnnotate, 0, 1.5, nil); err == nil {
+		t.Errorf("expected an error for an invalid similarity")
+	}
+
+	r, err := NewRelists("", 0, 0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Action != RelistAnnotate || r.Window != 72*time.Hour || r.Similarity != 0.8 {
+		t.Errorf("unexpected defaults %+v", r)
+	}
+}
+
+func TestDHash(t *testing.T) {
+	gradient := func(shift int) image.Image {
+		img := image.NewGray(image.Rect(0, 0, 90, 80))
+		for y := 0; y < 80; y++ {
+			for x := 0; x < 90; x++ {
+				img.SetGray(x, y, color.Gray{Y: uint8((x*2 + shift + y) % 256)})
+			}
+		}
+		return img
+	}
+
+	same := imageDistance(dHash(gradient(0)), dHash(gradient(3)))
+	if same > maxImageDistance {
+		t.Errorf("expected similar images to have close hashes, got a distance of %d", same)
+	}
+
+	flipped := image.NewGray(image.Rect(0, 0, 90, 80))
+	src := gradient(0)
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 90; x++ {
+			flipped.Set(89-x, y, src.At(x, y))
+		}
+	}
+
+	if d := imageDistance(dHash(src), dHash(flipped)); d <= maxImageDistance {
+		t.Errorf("expected different images to have distant hashes, got a distance of %d", d)
+	}
+}
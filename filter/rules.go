@@ -0,0 +1,256 @@
+package filter
+
+import (
+	"ebay-watchdog/scraper"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Action is what a rule does with the listings matching its expression.
+type Action string
+
+const (
+	// ActionDrop does not notify the listing.
+	ActionDrop Action = "drop"
+	// ActionNotify notifies the listing.
+	ActionNotify Action = "notify"
+	// ActionSilent notifies the listing without sound.
+	ActionSilent Action = "silent"
+	// ActionTag adds a tag to the listing, and goes on with the next rules.
+	ActionTag Action = "tag"
+)
+
+// Rule applies its action to the listings matching its expression.
+type Rule struct {
+	When   string
+	Action Action
+	// Tag is the tag added by the tag action.
+	Tag string
+}
+
+// Decision is what the rules decided for a listing.
+type Decision struct {
+	Drop   bool
+	Silent bool
+	Tags   []string
+}
+
+// Engine evaluates rules, in order, on the listings. The first matching drop, notify or silent rule decides, and the
+// matching tag rules before it add their tag. The listings matching no deciding rule are notified.
+type Engine struct {
+	rules []compiledRule
+	rates *Rates
+}
+
+type compiledRule struct {
+	Rule
+	expr *Expr
+}
+
+// NewEngine compiles and type checks the given rules. The amounts in different currencies are compared with the given
+// rates, which must be able to convert the currencies of the amounts of the rules. The error points to the offending
+// rule and expression.
+func NewEngine(rules []Rule, rates *Rates) (*Engine, error) {
+	e := &Engine{rates: rates}
+	for i, r := range rules {
+		switch r.Action {
+		case ActionDrop, ActionNotify, ActionSilent:
+		case ActionTag:
+			if r.Tag == "" {
+				return nil, fmt.Errorf("rule #%d: the tag action needs a tag", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("rule #%d: unknown action %q, expected drop, notify, silent or tag", i+1, r.Action)
+		}
+
+		expr, err := CompileExpr(r.When)
+		if err != nil {
+			return nil, fmt.Errorf("rule #%d: expression %q: %v", i+1, r.When, err)
+		}
+
+		for _, c := range expr.currencies {
+			if _, ok := rates.Convert(1, c.val); !ok {
+				err := errorAt(c, "no exchange rate to convert %s amounts, see [currency]", c.val)
+				return nil, fmt.Errorf("rule #%d: expression %q: %v", i+1, r.When, err)
+			}
+		}
+
+		e.rules = append(e.rules, compiledRule{Rule: r, expr: expr})
+	}
+
+	return e, nil
+}
+
+// Decide evaluates the rules on the given listing.
+func (e *Engine) Decide(listing scraper.Listing) Decision {
+	var d Decision
+	if e == nil {
+		return d
+	}
+
+	env := &env{listing: listing, rates: e.rates}
+	for _, r := range e.rules {
+		if !r.expr.eval(env) {
+			continue
+		}
+
+		switch r.Action {
+		case ActionTag:
+			d.Tags = append(d.Tags, r.Tag)
+			continue
+		case ActionDrop:
+			d.Drop = true
+		case ActionSilent:
+			d.Silent = true
+		}
+
+		return d
+	}
+
+	return d
+}
+
+// env is the listing an expression is evaluated on.
+type env struct {
+	listing scraper.Listing
+	rates   *Rates
+
+	price       *scraper.Price
+	priceParsed bool
+}
+
+// parsedPrice returns the numeric price of the listing, parsed once. It returns nil if the price cannot be parsed.
+func (e *env) parsedPrice() *scraper.Price {
+	if !e.priceParsed {
+		e.priceParsed = true
+		if p, err := e.listing.ParsedPrice(); err == nil {
+			e.price = &p
+		}
+	}
+
+	return e.price
+}
+
+// sameCurrency returns the given numbers, if they can be compared: either both are amounts in the same currency, or
+// at least one of them is a plain number. Amounts in different currencies are converted into the base currency, when
+// their rates are known.
+func (e *env) sameCurrency(l value, r value) (float64, float64, bool) {
+	if l.currency == "" || r.currency == "" || l.currency == r.currency {
+		return l.n, r.n, true
+	}
+
+	ln, lok := e.rates.Convert(l.n, l.currency)
+	rn, rok := e.rates.Convert(r.n, r.currency)
+
+	return ln, rn, lok && rok
+}
+
+// field is a data of the listing which can be used in the expressions.
+type field struct {
+	kind kind
+	get  func(env *env) value
+}
+
+// freeShippingWordings are the shipping texts of the listings with free shipping, in the languages of the eBay sites.
+var freeShippingWordings = []string{"free", "gratuit", "kostenlos", "gratis", "gratuita", "darmowa", "bezpłatna"}
+
+// fields are the data of the listings which can be used in the expressions.
+var fields = map[string]field{
+	"title":     stringField(func(l scraper.Listing) string { return l.Title }),
+	"subtitle":  stringField(func(l scraper.Listing) string { return l.Subtitle }),
+	"condition": stringField(func(l scraper.Listing) string { return l.Condition }),
+	"shipping":  stringField(func(l scraper.Listing) string { return l.Shipping }),
+	"location":  stringField(func(l scraper.Listing) string { return l.Location }),
+	"url":       stringField(func(l scraper.Listing) string { return l.URL }),
+	"search":    stringField(func(l scraper.Listing) string { return l.SearchURL }),
+	// The seller is usually only known once the listing is enriched.
+	"seller": {kind: kindString, get: func(env *env) value {
+		if env.listing.Seller == "" {
+			return value{missing: true}
+		}
+		return value{s: env.listing.Seller}
+	}},
+	"domain": stringField(func(l scraper.Listing) string {
+		u, err := url.Parse(l.URL)
+		if err != nil {
+			return ""
+		}
+		if site, ok := scraper.LookupSiteByHost(u.Host); ok {
+			return site.Domain
+		}
+		return ""
+	}),
+	"price": {kind: kindNumber, get: func(env *env) value {
+		p := env.parsedPrice()
+		if p == nil {
+			return value{missing: true}
+		}
+		return value{n: p.Amount, currency: p.Currency}
+	}},
+	"base_price": {kind: kindNumber, get: func(env *env) value {
+		p := env.parsedPrice()
+		if p == nil {
+			return value{missing: true}
+		}
+		converted, ok := env.rates.Convert(p.Amount, p.Currency)
+		if !ok {
+			return value{missing: true}
+		}
+		return value{n: converted, currency: env.rates.Base}
+	}},
+	"currency": {kind: kindString, get: func(env *env) value {
+		p := env.parsedPrice()
+		if p == nil {
+			return value{missing: true}
+		}
+		return value{s: p.Currency}
+	}},
+	"free_shipping": {kind: kindBool, get: func(env *env) value {
+		shipping := strings.ToLower(env.listing.Shipping)
+		for _, w := range freeShippingWordings {
+			if strings.Contains(shipping, w) {
+				return value{b: true}
+			}
+		}
+		return value{}
+	}},
+	"sponsored":   boolField(func(l scraper.Listing) bool { return l.Sponsored }),
+	"fewer_words": boolField(func(l scraper.Listing) bool { return l.FewerWords }),
+	"percentile": {kind: kindNumber, get: func(env *env) value {
+		if env.listing.Deal == nil {
+			return value{missing: true}
+		}
+		return value{n: env.listing.Deal.Percentile}
+	}},
+	"feedback_score": {kind: kindNumber, get: func(env *env) value {
+		if !env.listing.Enriched || env.listing.Seller == "" {
+			return value{missing: true}
+		}
+		return value{n: float64(env.listing.SellerFeedbackScore)}
+	}},
+}
+
+func stringField(get func(l scraper.Listing) string) field {
+	return field{kind: kindString, get: func(env *env) value {
+		return value{s: get(env.listing)}
+	}}
+}
+
+func boolField(get func(l scraper.Listing) bool) field {
+	return field{kind: kindBool, get: func(env *env) value {
+		return value{b: get(env.listing)}
+	}}
+}
+
+// fieldNames returns the names of the fields, sorted.
+func fieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
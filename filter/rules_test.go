@@ -0,0 +1,179 @@
+package filter
+
+import (
+	"ebay-watchdog/scraper"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpr(t *testing.T) {
+	expr := `price < 200 EUR and (free_shipping or location contains 'Germany') and title !~ 'broken'`
+	e, err := CompileExpr(expr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		listing scraper.Listing
+		exp     bool
+	}{
+		{listing: scraper.Listing{URL: "https://www.ebay.fr/itm/1", Title: "Lens", Price: "150,00 EUR", Shipping: "Livraison gratuite"}, exp: true},
+		{listing: scraper.Listing{URL: "https://www.ebay.de/itm/1", Title: "Lens", Price: "EUR 1.150,00", Location: "Berlin, Germany"}, exp: false},
+		{listing: scraper.Listing{URL: "https://www.ebay.de/itm/1", Title: "Lens", Price: "EUR 150,00", Location: "Berlin, Germany"}, exp: true},
+		{listing: scraper.Listing{URL: "https://www.ebay.fr/itm/1", Title: "Lens", Price: "150,00 EUR", Location: "Italy"}, exp: false},
+		{listing: scraper.Listing{URL: "https://www.ebay.fr/itm/1", Title: "BROKEN lens", Price: "150,00 EUR", Shipping: "Free"}, exp: false},
+		{listing: scraper.Listing{URL: "https://www.ebay.com/itm/1", Title: "Lens", Price: "$150.00", Shipping: "Free shipping"}, exp: false},
+		{listing: scraper.Listing{URL: "https://www.ebay.fr/itm/1", Title: "Lens", Price: "", Shipping: "Free"}, exp: false},
+	}
+
+	for _, tt := range tests {
+		got := e.eval(&env{listing: tt.listing})
+		if got != tt.exp {
+			t.Errorf("expected %v but got %v for %+v", tt.exp, got, tt.listing)
+		}
+	}
+}
+
+func TestExprOperators(t *testing.T) {
+	listing := scraper.Listing{URL: "https://www.ebay.com/itm/1", Title: "Nintendo DS", Price: "$50.00", Sponsored: true}
+
+	tests := map[string]bool{
+		`price >= 50 && price <= 50.0`:              true,
+		`price == 50 USD`:                           true,
+		`price != 50`:                               false,
+		`currency == 'usd'`:                         true,
+		`domain == "com"`:                           true,
+		`not sponsored || fewer_words`:              false,
+		`!(title ~ '^nintendo') or price > 1 USD`:   true,
+		`title contains "ds" and sponsored == true`: true,
+		`feedback_score > 0`:                        false,
+	}
+
+	for expr, exp := range tests {
+		e, err := CompileExpr(expr)
+		if err != nil {
+			t.Errorf("could not compile %s: %v", expr, err)
+			continue
+		}
+
+		if got := e.eval(&env{listing: listing}); got != exp {
+			t.Errorf("expected %v but got %v for %s", exp, got, expr)
+		}
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	tests := map[string]string{
+		`pricee < 200`:           "column 1: unknown field pricee",
+		`price < 'cheap'`:        "column 7: < expects number operands",
+		`title contains 3`:       "column 7: contains expects string operands",
+		`title ~ subtitle`:       "column 9: the right operand of ~ must be a quoted regular expression",
+		`title ~ '(broken'`:      "column 9: invalid regular expression",
+		`price < 200 and`:        "column 16: unexpected end of expression",
+		`(free_shipping`:         "column 15: expected \")\"",
+		`title`:                  "the expression is a string, not a boolean",
+		`sponsored and price`:    "column 11: and expects boolean operands",
+		`title == 'a' # comment`: "column 14: unexpected character",
+		`title == 'unterminated`: "column 10: unterminated string",
+		`sponsored == 'true'`:    "column 11: cannot compare a boolean with a string",
+	}
+
+	for expr, exp := range tests {
+		_, err := CompileExpr(expr)
+		if err == nil || !strings.Contains(err.Error(), exp) {
+			t.Errorf("expected error %q for %s but got %v", exp, expr, err)
+		}
+	}
+}
+
+func TestEngine(t *testing.T) {
+	e, err := NewEngine([]Rule{
+		{When: `title contains 'rare'`, Action: ActionTag, Tag: "rare"},
+		{When: `title contains 'replica'`, Action: ActionDrop},
+		{When: `price > 100`, Action: ActionSilent},
+		{When: `price > 10`, Action: ActionNotify},
+		{When: `true`, Action: ActionTag, Tag: "cheap"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		listing scraper.Listing
+		exp     Decision
+	}{
+		{listing: scraper.Listing{Title: "Rare replica", Price: "$5.00"}, exp: Decision{Drop: true, Tags: []string{"rare"}}},
+		{listing: scraper.Listing{Title: "Rare coin", Price: "$500.00"}, exp: Decision{Silent: true, Tags: []string{"rare"}}},
+		{listing: scraper.Listing{Title: "Coin", Price: "$50.00"}, exp: Decision{}},
+		{listing: scraper.Listing{Title: "Coin", Price: "$5.00"}, exp: Decision{Tags: []string{"cheap"}}},
+	}
+
+	for _, tt := range tests {
+		got := e.Decide(tt.listing)
+		if !reflect.DeepEqual(got, tt.exp) {
+			t.Errorf("expected %+v but got %+v for %+v", tt.exp, got, tt.listing)
+		}
+	}
+}
+
+func TestEngineEnrichmentFields(t *testing.T) {
+	e, err := NewEngine([]Rule{
+		{When: `feedback_score < 50`, Action: ActionDrop},
+		{When: `seller == 'tape_seller'`, Action: ActionTag, Tag: "favourite"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		listing scraper.Listing
+		exp     Decision
+	}{
+		{listing: scraper.Listing{Title: "Tape"}, exp: Decision{}},
+		{listing: scraper.Listing{Title: "Tape", Seller: "tape_seller"}, exp: Decision{Tags: []string{"favourite"}}},
+		{listing: scraper.Listing{Title: "Tape", Enriched: true}, exp: Decision{}},
+		{listing: scraper.Listing{Title: "Tape", Enriched: true, Seller: "glue_seller", SellerFeedbackScore: 10}, exp: Decision{Drop: true}},
+		{listing: scraper.Listing{Title: "Tape", Enriched: true, Seller: "tape_seller", SellerFeedbackScore: 500}, exp: Decision{Tags: []string{"favourite"}}},
+	}
+
+	for _, tt := range tests {
+		got := e.Decide(tt.listing)
+		if !reflect.DeepEqual(got, tt.exp) {
+			t.Errorf("expected %+v but got %+v for %+v", tt.exp, got, tt.listing)
+		}
+	}
+}
+
+func TestNewEngineErrors(t *testing.T) {
+	_, err := NewEngine([]Rule{
+		{When: `true`, Action: ActionNotify},
+		{When: `price < 'x'`, Action: ActionDrop},
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), `rule #2: expression "price < 'x'": column 7`) {
+		t.Errorf("expected the error to point to the second rule, got %v", err)
+	}
+
+	_, err = NewEngine([]Rule{{When: `price < 200 EUR`, Action: ActionDrop}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "column 13: no exchange rate to convert EUR amounts") {
+		t.Errorf("expected an error for an amount without rates, got %v", err)
+	}
+
+	rates, _ := NewRates("EUR", map[string]float64{"USD": 0.9}, "")
+	_, err = NewEngine([]Rule{{When: `price < 200 EUR or price < 100 CHF`, Action: ActionDrop}}, rates)
+	if err == nil || !strings.Contains(err.Error(), "column 32: no exchange rate to convert CHF amounts") {
+		t.Errorf("expected an error for an amount in an unknown currency, got %v", err)
+	}
+
+	if _, err := NewEngine([]Rule{{When: `price < 200 EUR or price < 100 USD`, Action: ActionDrop}}, rates); err != nil {
+		t.Errorf("unexpected error for convertible amounts: %v", err)
+	}
+
+	if _, err := NewEngine([]Rule{{When: `true`, Action: "ignore"}}, nil); err == nil {
+		t.Errorf("expected an error for an unknown action")
+	}
+
+	if _, err := NewEngine([]Rule{{When: `true`, Action: ActionTag}}, nil); err == nil {
+		t.Errorf("expected an error for a tag action without tag")
+	}
+}
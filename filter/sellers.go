@@ -0,0 +1,127 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// SellerList are seller usernames: Allow restricts the notified listings to the given sellers, Deny excludes the
+// listings of the given sellers.
+type SellerList struct {
+	Allow []string
+	Deny  []string
+}
+
+// Sellers applies the seller lists to the listings: the global lists, the lists of each search, and the lists of a
+// file which is read again whenever it changes, so that they can be edited at runtime. A denied seller is never
+// notified. When there are allowed sellers, only their listings are notified: the listings with an unknown seller
+// are not.
+type Sellers struct {
+	global   SellerList
+	searches map[string]SellerList
+
+	file     string
+	fileMod  time.Time
+	fromFile SellerList
+}
+
+// NewSellers returns the given seller lists, and reads the given file, if any. The file is a toml file with allow and
+// deny arrays.
+func NewSellers(global SellerList, searches map[string]SellerList, file string) (*Sellers, error) {
+	s := &Sellers{
+		global:   global,
+		searches: searches,
+		file:     file,
+	}
+
+	err := s.Refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Refresh reads the seller lists file again if it changed since the last time it was read.
+func (s *Sellers) Refresh() error {
+	if s == nil || s.file == "" {
+		return nil
+	}
+
+	info, err := os.Stat(s.file)
+	if err != nil {
+		return fmt.Errorf("could not read sellers file %s: %v", s.file, err)
+	}
+
+	if !info.ModTime().After(s.fileMod) {
+		return nil
+	}
+
+	dat, err := ioutil.ReadFile(s.file)
+	if err != nil {
+		return fmt.Errorf("could not read sellers file %s: %v", s.file, err)
+	}
+
+	var list SellerList
+	err = toml.Unmarshal(dat, &list)
+	if err != nil {
+		return fmt.Errorf("could not parse sellers file %s: %v", s.file, err)
+	}
+
+	if !s.fileMod.IsZero() {
+		log.Printf("Reloaded the seller lists from %s\n", s.file)
+	}
+
+	s.fromFile = list
+	s.fileMod = info.ModTime()
+
+	return nil
+}
+
+// Match returns whether the given seller, found by the given search, can be notified. When it cannot, it also
+// returns the reason.
+func (s *Sellers) Match(seller string, searchURL string) (bool, string) {
+	if s == nil {
+		return true, ""
+	}
+
+	lists := []SellerList{s.global, s.searches[searchURL], s.fromFile}
+
+	hasAllowed := false
+	allowed := false
+	for _, l := range lists {
+		if seller != "" && contains(l.Deny, seller) {
+			return false, fmt.Sprintf("denied seller %s", seller)
+		}
+
+		if len(l.Allow) > 0 {
+			hasAllowed = true
+			allowed = allowed || (seller != "" && contains(l.Allow, seller))
+		}
+	}
+
+	if hasAllowed && !allowed {
+		if seller == "" {
+			return false, "unknown seller, while only allowed sellers are notified"
+		}
+		return false, fmt.Sprintf("seller %s is not allowed", seller)
+	}
+
+	return true, ""
+}
+
+// contains returns whether the given seller is in the given list, case insensitively.
+func contains(list []string, seller string) bool {
+	for _, s := range list {
+		if strings.EqualFold(strings.TrimSpace(s), seller) {
+			return true
+		}
+	}
+
+	return false
+}
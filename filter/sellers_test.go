@@ -0,0 +1,93 @@
+package filter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSellersMatch(t *testing.T) {
+	searches := map[string]SellerList{
+		"allow": {Allow: []string{"Good_Shop"}},
+		"deny":  {Deny: []string{"other"}},
+	}
+	sellers, err := NewSellers(SellerList{Deny: []string{"scalper"}}, searches, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		seller    string
+		searchURL string
+		exp       bool
+	}{
+		{seller: "someone", searchURL: "none", exp: true},
+		{seller: "", searchURL: "none", exp: true},
+		{seller: "Scalper", searchURL: "none", exp: false},
+		{seller: "scalper", searchURL: "allow", exp: false},
+		{seller: "good_shop", searchURL: "allow", exp: true},
+		{seller: "someone", searchURL: "allow", exp: false},
+		{seller: "", searchURL: "allow", exp: false},
+		{seller: "other", searchURL: "deny", exp: false},
+		{seller: "other", searchURL: "none", exp: true},
+	}
+
+	for _, tt := range tests {
+		got, reason := sellers.Match(tt.seller, tt.searchURL)
+		if got != tt.exp {
+			t.Errorf("expected %v but got %v (%s) for seller %q in search %q", tt.exp, got, reason, tt.seller, tt.searchURL)
+		}
+	}
+
+	var none *Sellers
+	if ok, _ := none.Match("scalper", ""); !ok {
+		t.Errorf("expected nil sellers to match")
+	}
+}
+
+func TestSellersFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sellers")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "sellers.toml")
+	if err := ioutil.WriteFile(file, []byte("deny = [\"scalper\"]\n"), 0644); err != nil {
+		t.Fatalf("could not write sellers file: %v", err)
+	}
+
+	sellers, err := NewSellers(SellerList{}, nil, file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ok, _ := sellers.Match("scalper", ""); ok {
+		t.Errorf("expected the seller of the file to be denied")
+	}
+
+	if err := ioutil.WriteFile(file, []byte("deny = [\"other\"]\n"), 0644); err != nil {
+		t.Fatalf("could not write sellers file: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatalf("could not touch sellers file: %v", err)
+	}
+
+	if err := sellers.Refresh(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ok, _ := sellers.Match("scalper", ""); !ok {
+		t.Errorf("expected the seller removed from the file to be notified")
+	}
+	if ok, _ := sellers.Match("other", ""); ok {
+		t.Errorf("expected the seller added to the file to be denied")
+	}
+
+	if _, err := NewSellers(SellerList{}, nil, filepath.Join(dir, "missing.toml")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
@@ -4,6 +4,7 @@ go 1.16
 
 require (
 	github.com/PuerkitoBio/goquery v1.8.0
+	github.com/andybalholm/brotli v1.0.4
 	github.com/goodsign/monday v1.0.0
 	github.com/joho/godotenv v1.4.0
 	github.com/pelletier/go-toml/v2 v2.0.0-beta.4
@@ -5,10 +5,19 @@ import (
 	"ebay-watchdog/config"
 	"ebay-watchdog/coordinator"
 	"log"
+	"os"
 	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		err := runCommand(os.Args[1:])
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
 	log.Println("Starting ebay-watchdog")
 
 	cfg, err := config.Load()
@@ -26,8 +35,42 @@ func main() {
 		log.Fatalf("Could not load scraper urls: %v", err)
 	}
 
+	var notified []cache.NotifiedListing
+	if cfg.Relist.Enabled {
+		notified, err = cache.LoadNotified()
+		if err != nil {
+			log.Fatalf("Could not load notified listings: %v", err)
+		}
+	}
+
+	var sold map[string][]cache.SoldListing
+	if cfg.Deals.Enabled {
+		sold, err = cache.LoadSold()
+		if err != nil {
+			log.Fatalf("Could not load sold listings: %v", err)
+		}
+	}
+
 	sleepPeriod := time.Duration(cfg.Delay) * time.Second
 
-	c := coordinator.NewCoordinator(cfg.Searches, sleepPeriod, tpl)
-	c.Start(scrapedURLs)
+	client := coordinator.NewHTTPClient(cfg.HTTP)
+
+	c, err := coordinator.NewCoordinator(cfg, sleepPeriod, tpl, client)
+	if err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+
+	if cfg.Watchlist.Enabled {
+		w := coordinator.NewWatcher(time.Duration(cfg.Watchlist.Interval)*time.Minute, client)
+		if _, err := w.Add(cfg.Watchlist.Items); err != nil {
+			log.Fatalf("Invalid watchlist: %v", err)
+		}
+
+		go w.Start()
+		if cfg.Watchlist.Buttons {
+			go w.ListenTelegram(os.Getenv("TELEGRAM_TOKEN"), os.Getenv("TELEGRAM_CHAT_ID"))
+		}
+	}
+
+	c.Start(scrapedURLs, notified, sold)
 }
@@ -0,0 +1,202 @@
+package scraper
+
+import (
+	"ebay-watchdog/web"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPageSize is the number of items requested to the Browse API for each search.
+const apiPageSize = 50
+
+// APISource fetches the listings from the eBay Browse API item_summary/search endpoint.
+type APISource struct {
+	Client *web.BrowseClient
+}
+
+func NewAPISource(client *web.BrowseClient) *APISource {
+	return &APISource{Client: client}
+}
+
+// Fetch implements Source. The search parameters are read from the given search URL.
+func (a *APISource) Fetch(searchURL SearchURL, URL string, domain string) (Page, error) {
+	site, ok := LookupSite(domain)
+	if !ok {
+		return Page{}, fmt.Errorf("no eBay marketplace for domain %s", domain)
+	}
+
+	resp, err := a.search(URL, site.MarketplaceID)
+	if err != nil {
+		return Page{}, err
+	}
+
+	listings := make([]Listing, 0, len(resp.ItemSummaries))
+	for _, item := range resp.ItemSummaries {
+		listing, err := itemSummaryToListing(item)
+		if err != nil {
+			return Page{}, err
+		}
+
+		listings = append(listings, listing)
+	}
+
+	return Page{Listings: listings, Next: resp.Next}, nil
+}
+
+// search queries the Browse API for the given search URL. The URL can also be the next page URL of a previous
+// response.
+func (a *APISource) search(URL string, marketplaceID string) (*web.SearchResponse, error) {
+	var resp *web.SearchResponse
+	var err error
+	if strings.HasPrefix(URL, a.Client.BaseURL) {
+		resp, err = a.Client.SearchURL(URL, marketplaceID)
+	} else {
+		params, paramsErr := browseParams(URL)
+		if paramsErr != nil {
+			return nil, paramsErr
+		}
+
+		resp, err = a.Client.Search(params, marketplaceID)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not search the Browse API for URL %s: %w", URL, err)
+	}
+
+	return resp, nil
+}
+
+// browseParams translates the given eBay search URL into Browse API query parameters, newest listings first.
+func browseParams(URL string) (url.Values, error) {
+	u, err := url.Parse(URL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse search URL %s: %v", URL, err)
+	}
+	query := u.Query()
+
+	params := url.Values{}
+	params.Set("sort", "newlyListed")
+	params.Set("limit", strconv.Itoa(apiPageSize))
+
+	if q := query.Get("_nkw"); q != "" {
+		params.Set("q", q)
+	}
+
+	if category := query.Get("_sacat"); category != "" && category != "0" {
+		params.Set("category_ids", category)
+	}
+
+	if params.Get("q") == "" && params.Get("category_ids") == "" {
+		return nil, fmt.Errorf("search URL %s has neither keywords (_nkw) nor category (_sacat)", URL)
+	}
+
+	var filters []string
+	if lo, hi := query.Get("_udlo"), query.Get("_udhi"); lo != "" || hi != "" {
+		filters = append(filters, fmt.Sprintf("price:[%s..%s]", lo, hi))
+	}
+
+	var buyingOptions []string
+	if query.Get("LH_Auction") == "1" {
+		buyingOptions = append(buyingOptions, "AUCTION")
+	}
+	if query.Get("LH_BIN") == "1" {
+		buyingOptions = append(buyingOptions, "FIXED_PRICE")
+	}
+	if query.Get("LH_BO") == "1" {
+		buyingOptions = append(buyingOptions, "BEST_OFFER")
+	}
+	if len(buyingOptions) > 0 {
+		filters = append(filters, fmt.Sprintf("buyingOptions:{%s}", strings.Join(buyingOptions, "|")))
+	}
+
+	if conditions := query.Get("LH_ItemCondition"); conditions != "" {
+		filters = append(filters, fmt.Sprintf("conditionIds:{%s}", conditions))
+	}
+
+	if query.Get("LH_FS") == "1" {
+		filters = append(filters, "maxDeliveryCost:0")
+	}
+
+	if seller := query.Get("_ssn"); seller != "" {
+		filters = append(filters, fmt.Sprintf("sellers:{%s}", seller))
+	}
+
+	if len(filters) > 0 {
+		params.Set("filter", strings.Join(filters, ","))
+	}
+
+	return params, nil
+}
+
+// itemSummaryToListing maps the given Browse API item to a Listing.
+func itemSummaryToListing(item web.ItemSummary) (Listing, error) {
+	date, err := time.Parse(time.RFC3339, item.ItemCreationDate)
+	if err != nil {
+		return Listing{}, fmt.Errorf("could not parse creation date %s of item %s: %v", item.ItemCreationDate, item.ItemID, err)
+	}
+
+	listing := Listing{
+		URL:       item.ItemWebURL,
+		Title:     item.Title,
+		Subtitle:  item.ShortDescription,
+		Date:      date,
+		ID:        legacyItemID(item.ItemID),
+		Condition: item.Condition,
+		Sponsored: item.PriorityListing,
+	}
+
+	price := item.Price
+	if price == nil {
+		price = item.CurrentBidPrice
+	}
+	if price != nil {
+		listing.Price = formatAmount(*price)
+	}
+
+	if item.Image != nil {
+		listing.Image = item.Image.ImageURL
+	}
+
+	if len(item.ShippingOptions) > 0 && item.ShippingOptions[0].ShippingCost != nil {
+		cost := item.ShippingOptions[0].ShippingCost
+		if v, err := strconv.ParseFloat(cost.Value, 64); err == nil && v == 0 {
+			listing.Shipping = "Free shipping"
+		} else {
+			listing.Shipping = fmt.Sprintf("+%s shipping", formatAmount(*cost))
+		}
+	}
+
+	if item.Seller != nil {
+		listing.Seller = item.Seller.Username
+		listing.SellerFeedbackScore = item.Seller.FeedbackScore
+		listing.SellerFeedbackPercent = item.Seller.FeedbackPercentage
+	}
+
+	if item.ItemLocation != nil {
+		listing.Location = item.ItemLocation.Country
+		if item.ItemLocation.City != "" {
+			listing.Location = fmt.Sprintf("%s, %s", item.ItemLocation.City, item.ItemLocation.Country)
+		}
+	}
+
+	return listing, nil
+}
+
+// legacyItemID returns the legacy item ID from the given Browse API item ID, e.g. v1|402943017690|0 gives
+// 402943017690.
+func legacyItemID(itemID string) string {
+	split := strings.Split(itemID, "|")
+	if len(split) == 3 {
+		return split[1]
+	}
+
+	return itemID
+}
+
+// formatAmount formats the given amount, e.g. 19.99 USD.
+func formatAmount(a web.Amount) string {
+	return fmt.Sprintf("%s %s", a.Value, a.Currency)
+}
@@ -0,0 +1,220 @@
+package scraper
+
+import (
+	"ebay-watchdog/web"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const browseSearchResponse = `{
+  "total": 2,
+  "itemSummaries": [
+    {
+      "itemId": "v1|402943017690|0",
+      "title": "Puma Powercamp 2.0 Training Ball",
+      "itemWebUrl": "https://www.ebay.com/itm/402943017690",
+      "price": {"value": "19.99", "currency": "USD"},
+      "image": {"imageUrl": "https://i.ebayimg.com/images/g/RdAAAOSwudVg0-jc/s-l225.jpg"},
+      "condition": "New",
+      "itemCreationDate": "2021-06-23T19:07:00.000Z",
+      "itemLocation": {"country": "US"},
+      "shippingOptions": [{"shippingCost": {"value": "0.00", "currency": "USD"}}]
+    },
+    {
+      "itemId": "v1|402943017000|0",
+      "title": "Soccer ball",
+      "itemWebUrl": "https://www.ebay.com/itm/402943017000",
+      "currentBidPrice": {"value": "5.00", "currency": "USD"},
+      "itemCreationDate": "2021-06-23T18:00:00.000Z",
+      "itemLocation": {"city": "Austin", "country": "US"},
+      "shippingOptions": [{"shippingCost": {"value": "4.50", "currency": "USD"}}],
+      "priorityListing": true
+    }
+  ]
+}`
+
+func newBrowseAPIMock(t *testing.T, tokenRequests *int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/identity/v1/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		*tokenRequests++
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "client-id" || pass != "client-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		fmt.Fprint(w, `{"access_token": "token-123", "expires_in": 7200, "token_type": "Application Access Token"}`)
+	})
+	mux.HandleFunc("/buy/browse/v1/item_summary/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Header.Get("X-EBAY-C-MARKETPLACE-ID") != "EBAY_US" {
+			t.Errorf("unexpected marketplace %s", r.Header.Get("X-EBAY-C-MARKETPLACE-ID"))
+		}
+
+		query := r.URL.Query()
+		if query.Get("q") != "soccer ball" || query.Get("sort") != "newlyListed" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+
+		fmt.Fprint(w, browseSearchResponse)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestAPISourceFetch(t *testing.T) {
+	tokenRequests := 0
+	server := newBrowseAPIMock(t, &tokenRequests)
+	defer server.Close()
+
+	source := NewAPISource(web.NewBrowseClient(server.URL, "client-id", "client-secret"))
+	URL := "https://www.ebay.com/sch/i.html?_from=R40&_nkw=soccer+ball&_sacat=0&_sop=10"
+
+	page, err := source.Fetch(SearchURL{URL: URL, Source: SourceAPI}, URL, "com")
+	if err != nil {
+		t.Fatalf("could not fetch listings: %v", err)
+	}
+
+	exp := []Listing{
+		{
+			URL:       "https://www.ebay.com/itm/402943017690",
+			Title:     "Puma Powercamp 2.0 Training Ball",
+			Price:     "19.99 USD",
+			Date:      time.Date(2021, 6, 23, 19, 7, 0, 0, time.UTC),
+			ID:        "402943017690",
+			Image:     "https://i.ebayimg.com/images/g/RdAAAOSwudVg0-jc/s-l225.jpg",
+			Condition: "New",
+			Shipping:  "Free shipping",
+			Location:  "US",
+		},
+		{
+			URL:       "https://www.ebay.com/itm/402943017000",
+			Title:     "Soccer ball",
+			Price:     "5.00 USD",
+			Date:      time.Date(2021, 6, 23, 18, 0, 0, 0, time.UTC),
+			ID:        "402943017000",
+			Shipping:  "+4.50 USD shipping",
+			Location:  "Austin, US",
+			Sponsored: true,
+		},
+	}
+
+	if !reflect.DeepEqual(exp, page.Listings) {
+		t.Errorf("expected %+v but got %+v", exp, page.Listings)
+	}
+
+	_, err = source.Fetch(SearchURL{URL: URL, Source: SourceAPI}, URL, "com")
+	if err != nil {
+		t.Fatalf("could not fetch listings: %v", err)
+	}
+
+	if tokenRequests != 1 {
+		t.Errorf("expected the access token to be reused, got %d token requests", tokenRequests)
+	}
+}
+
+func TestAPISourceFetchErrors(t *testing.T) {
+	tokenRequests := 0
+	server := newBrowseAPIMock(t, &tokenRequests)
+	defer server.Close()
+
+	URL := "https://www.ebay.com/sch/i.html?_nkw=soccer+ball&_sop=10"
+
+	t.Run("Wrong credentials", func(t *testing.T) {
+		source := NewAPISource(web.NewBrowseClient(server.URL, "client-id", "wrong"))
+		_, err := source.Fetch(SearchURL{URL: URL}, URL, "com")
+		if err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("Unknown domain", func(t *testing.T) {
+		source := NewAPISource(web.NewBrowseClient(server.URL, "client-id", "client-secret"))
+		_, err := source.Fetch(SearchURL{URL: URL}, URL, "xyz")
+		if err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+func TestAPISourceFetchStatusErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		blocked    bool
+	}{
+		{"Rate limited", http.StatusTooManyRequests, true},
+		{"Forbidden", http.StatusForbidden, false},
+		{"Server error", http.StatusInternalServerError, false},
+	}
+
+	URL := "https://www.ebay.com/sch/i.html?_nkw=soccer+ball&_sop=10"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/identity/v1/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"access_token": "token-123", "expires_in": 7200}`)
+			})
+			mux.HandleFunc("/buy/browse/v1/item_summary/search", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+				fmt.Fprint(w, `{"errors": [{"errorId": 2001}]}`)
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			source := NewAPISource(web.NewBrowseClient(server.URL, "client-id", "client-secret"))
+			_, err := source.Fetch(SearchURL{URL: URL, Source: SourceAPI}, URL, "com")
+
+			var blockedErr *web.BlockedError
+			var statusErr *web.StatusError
+			switch {
+			case tt.blocked && !errors.As(err, &blockedErr):
+				t.Errorf("expected a BlockedError, got %v", err)
+			case tt.blocked && blockedErr.StatusCode != tt.statusCode:
+				t.Errorf("expected status code %d, got %d", tt.statusCode, blockedErr.StatusCode)
+			case !tt.blocked && !errors.As(err, &statusErr):
+				t.Errorf("expected a StatusError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBrowseParams(t *testing.T) {
+	got, err := browseParams("https://www.ebay.com/sch/i.html?_nkw=macbook+pro&_sacat=111422&_udlo=100&_udhi=500&LH_BIN=1&LH_ItemCondition=3000&LH_FS=1&_sop=10")
+	if err != nil {
+		t.Fatalf("could not build params: %v", err)
+	}
+
+	exp := map[string]string{
+		"q":            "macbook pro",
+		"category_ids": "111422",
+		"sort":         "newlyListed",
+		"limit":        "50",
+		"filter":       "price:[100..500],buyingOptions:{FIXED_PRICE},conditionIds:{3000},maxDeliveryCost:0",
+	}
+
+	for k, v := range exp {
+		if got.Get(k) != v {
+			t.Errorf("expected %s=%s but got %s", k, v, got.Get(k))
+		}
+	}
+
+	_, err = browseParams("https://www.ebay.com/sch/i.html?_sop=10")
+	if err == nil {
+		t.Errorf("expected an error for a search without keywords nor category")
+	}
+}
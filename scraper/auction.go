@@ -0,0 +1,183 @@
+package scraper
+
+import (
+	"ebay-watchdog/cache"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Modes of the searches.
+const (
+	// ModeNewest notifies the newly listed items.
+	ModeNewest = "newest"
+	// ModeEnding notifies the auctions which are about to end.
+	ModeEnding = "ending"
+)
+
+// DefaultEndingWindow is the period before the end of an auction during which it is notified, for the searches which
+// do not set it.
+const DefaultEndingWindow = time.Hour
+
+// Ending configures the ModeEnding searches: an auction is notified once, when it ends within the window, if it has
+// fewer bids than MaxBids and a price lower than MaxPrice, when they are set.
+type Ending struct {
+	// Window defaults to DefaultEndingWindow.
+	Window time.Duration
+	// MaxBids is the maximum number of bids, excluded. 0 means no bound.
+	MaxBids int
+	// MaxPrice is the maximum current price, in the currency of the site. 0 means no bound.
+	MaxPrice float64
+}
+
+// window returns the ending window of the search.
+func (e Ending) window() time.Duration {
+	if e.Window <= 0 {
+		return DefaultEndingWindow
+	}
+
+	return e.Window
+}
+
+// match returns whether the given auction, which ends within the window, must be notified.
+func (e Ending) match(listing Listing) bool {
+	if e.MaxBids > 0 && listing.Bids >= e.MaxBids {
+		return false
+	}
+
+	if e.MaxPrice > 0 {
+		p, err := listing.ParsedPrice()
+		if err != nil || p.Amount > e.MaxPrice {
+			return false
+		}
+	}
+
+	return true
+}
+
+// scrapeEnding returns the auctions of the given ending soonest search which end within the window, match the search
+// and were not notified yet. It also returns them as alerted auctions, for the cache, and the error which stopped the
+// fetching of the pages, if any.
+// Unlike the newest-first searches, the pages are read until an auction ends after the window, and the auctions are
+// told apart by the alerted auctions of the cache rather than by the last scraped listing.
+func (s *Scraper) scrapeEnding(
+	source Source,
+	searchURL SearchURL,
+	URL string,
+	domain string,
+	cached cache.CachedListing,
+) ([]Listing, []cache.AlertedAuction, error) {
+	log.Printf("Searching auctions ending soon with url %s (domain %s)\n", URL, domain)
+
+	now := s.Now()
+	end := now.Add(searchURL.Ending.window())
+
+	var listings []Listing
+	var alerted []cache.AlertedAuction
+	var fetchErr error
+	pageURL := URL
+	for pageNumber := 1; ; pageNumber++ {
+		page, err := source.Fetch(searchURL, pageURL, domain)
+		if err != nil {
+			log.Printf("could not fetch the listings of search URL %s: %s\n", pageURL, err)
+			fetchErr = err
+			break
+		}
+
+		stopped := false
+		for _, listing := range page.Listings {
+			// Sponsored listings are not sorted by end time.
+			if listing.Sponsored || listing.EndsAt.IsZero() || listing.EndsAt.Before(now) {
+				continue
+			}
+
+			if listing.EndsAt.After(end) {
+				stopped = true
+				break
+			}
+
+			if cached.HasAlerted(listing.ItemID()) || !searchURL.Ending.match(listing) {
+				continue
+			}
+
+			log.Printf("Auction %s ends in %s with %d bid(s)\n", listing.ID, listing.EndsAt.Sub(now).Round(time.Second), listing.Bids)
+			listing.SearchURL = searchURL.URL
+			listings = append(listings, listing)
+			alerted = append(alerted, cache.AlertedAuction{ID: listing.ItemID(), EndsAt: listing.EndsAt})
+		}
+
+		if stopped || page.Next == "" || pageNumber >= searchURL.maxPages() {
+			break
+		}
+
+		// We space each queries just in case, to prevent getting throttled
+		time.Sleep(s.Pause)
+		pageURL = page.Next
+	}
+
+	return listings, alerted, fetchErr
+}
+
+// timeLeftUnits are the units of the time left of the auctions, in the languages of the eBay sites, by duration.
+var timeLeftUnits = map[time.Duration][]string{
+	24 * time.Hour: {"d", "day", "days", "t", "tag", "tage", "j", "g", "gg", "dni"},
+	time.Hour:      {"h", "hr", "hrs", "hour", "hours", "std", "u", "uur", "godz", "o", "ore"},
+	time.Minute:    {"m", "min", "mins", "minute", "minutes", "mn"},
+	time.Second:    {"s", "sec", "secs", "sek"},
+}
+
+// timeLeftUnit returns the duration of the given unit of time left.
+func timeLeftUnit(unit string) (time.Duration, bool) {
+	for d, units := range timeLeftUnits {
+		for _, u := range units {
+			if u == unit {
+				return d, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+var timeLeftRegexp = regexp.MustCompile(`(\d+)\s*([[:alpha:]]+)`)
+
+// parseTimeLeft returns the time left before the end of an auction, e.g. 2d 5h left, 45m 10s, Noch 3 Std. 12 Min.
+func parseTimeLeft(str string) (time.Duration, error) {
+	var res time.Duration
+	found := false
+	for _, m := range timeLeftRegexp.FindAllStringSubmatch(strings.ToLower(str), -1) {
+		unit, ok := timeLeftUnit(m[2])
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid time left %s: %v", str, err)
+		}
+
+		res += time.Duration(n) * unit
+		found = true
+	}
+
+	if !found {
+		return 0, fmt.Errorf("could not parse time left %q", str)
+	}
+
+	return res, nil
+}
+
+var bidsRegexp = regexp.MustCompile(`\d+`)
+
+// parseBids returns the number of bids of an auction, e.g. 3 bids, 1 Gebot, 0 enchère. It returns 0 if it is unknown.
+func parseBids(str string) int {
+	n, err := strconv.Atoi(bidsRegexp.FindString(str))
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
@@ -0,0 +1,148 @@
+package scraper
+
+import (
+	"ebay-watchdog/cache"
+	"ebay-watchdog/web"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+	"testing"
+	"time"
+)
+
+func endingTestItem(id string, timeLeft string) string {
+	return fmt.Sprintf(`<li class="s-item"><div class="s-item__wrapper"><div class="s-item__info"><a class="s-item__link" href="https://www.ebay.com/itm/%s"><h3 class="s-item__title">Item %s</h3></a><div class="s-item__subtitle"></div><div class="s-item__details"><span class="s-item__price">$1.00</span><span class="s-item__time-left">%s</span></div></div></div></li>`, id, id, timeLeft)
+}
+
+func TestParseTimeLeft(t *testing.T) {
+	tests := map[string]time.Duration{
+		"2d 5h left":            53 * time.Hour,
+		"45m 10s":               45*time.Minute + 10*time.Second,
+		"Noch 3 Std. 12 Min.":   3*time.Hour + 12*time.Minute,
+		"Il reste 1j 2h":        26 * time.Hour,
+		"1 day 4 hours left":    28 * time.Hour,
+		"Pozostało 2 godz. 5 m": 2*time.Hour + 5*time.Minute,
+	}
+
+	for str, exp := range tests {
+		got, err := parseTimeLeft(str)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", str, err)
+			continue
+		}
+
+		if got != exp {
+			t.Errorf("expected %v but got %v for %q", exp, got, str)
+		}
+	}
+
+	for _, str := range []string{"", "Ended", "left"} {
+		if _, err := parseTimeLeft(str); err == nil {
+			t.Errorf("expected an error for %q", str)
+		}
+	}
+}
+
+func TestParseBids(t *testing.T) {
+	tests := map[string]int{"3 bids": 3, "1 Gebot": 1, "0 enchère": 0, "": 0, "12 offerte": 12}
+	for str, exp := range tests {
+		if got := parseBids(str); got != exp {
+			t.Errorf("expected %d but got %d for %q", exp, got, str)
+		}
+	}
+}
+
+func TestScrapeEnding(t *testing.T) {
+	URL := "https://www.ebay.com/sch/i.html?LH_Auction=1&_nkw=tape&_sop=1"
+	now := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+
+	auction := func(ID int, endsIn time.Duration, bids int, price string) Listing {
+		return Listing{
+			ID:     fmt.Sprintf("%d", ID),
+			URL:    fmt.Sprintf("https://www.ebay.com/itm/%d", ID),
+			Price:  price,
+			EndsAt: now.Add(endsIn),
+			Bids:   bids,
+		}
+	}
+
+	source := &fakeSource{pages: map[string]Page{
+		URL: {
+			Listings: []Listing{
+				{ID: "9", URL: "https://www.ebay.com/itm/9", Sponsored: true, EndsAt: now.Add(5 * time.Minute)},
+				auction(1, 5*time.Minute, 0, "$10.00"),
+				auction(2, 10*time.Minute, 8, "$10.00"),
+				auction(3, 20*time.Minute, 1, "$50.00"),
+				auction(4, 30*time.Minute, 2, "$15.00"),
+			},
+			Next: URL + "&_pgn=2",
+		},
+		URL + "&_pgn=2": {
+			Listings: []Listing{
+				auction(5, 40*time.Minute, 0, "$5.00"),
+				auction(6, 2*time.Hour, 0, "$5.00"),
+			},
+			Next: URL + "&_pgn=3",
+		},
+	}}
+
+	s := NewScraper(nil, nil)
+	s.Pause = 0
+	s.Now = func() time.Time { return now }
+
+	searchURL := SearchURL{URL: URL, Mode: ModeEnding, Ending: Ending{MaxBids: 5, MaxPrice: 20}}
+	cached := cache.CachedListing{Alerted: []cache.AlertedAuction{{ID: "4", EndsAt: now.Add(30 * time.Minute)}}}
+
+	listings, alerted, err := s.scrapeEnding(source, searchURL, URL, "com", cached)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var IDs []string
+	for _, l := range listings {
+		IDs = append(IDs, l.ID)
+	}
+	if fmt.Sprint(IDs) != "[1 5]" {
+		t.Errorf("expected the auctions 1 and 5 but got %v", IDs)
+	}
+
+	if len(alerted) != 2 || alerted[1].ID != "5" || !alerted[1].EndsAt.Equal(now.Add(40*time.Minute)) {
+		t.Errorf("unexpected alerted auctions %+v", alerted)
+	}
+
+	if len(source.requested) != 2 {
+		t.Errorf("expected to stop at the first auction ending after the window, requested %v", source.requested)
+	}
+}
+
+func TestParsePageEndingWithoutTimeLeft(t *testing.T) {
+	now := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	html := `<ul class="srp-results">` +
+		endingTestItem("1", "5m 10s") +
+		endingTestItem("2", "") +
+		endingTestItem("3", "Ends today 14:30") +
+		endingTestItem("4", "45m 10s") +
+		`</ul><a class="pagination__next" href="https://www.ebay.com/sch/i.html?LH_Auction=1&_nkw=tape&_sop=1&_pgn=2"></a>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("could not create document: %v", err)
+	}
+
+	source := NewHTMLSource(nil, web.DefaultClient)
+	source.Now = func() time.Time { return now }
+	URL := "https://www.ebay.com/sch/i.html?LH_Auction=1&_nkw=tape&_sop=1"
+	page := source.parsePage(doc, SearchURL{URL: URL, Mode: ModeEnding}, URL, "com")
+
+	var IDs []string
+	for _, l := range page.Listings {
+		IDs = append(IDs, l.ID)
+	}
+	if fmt.Sprint(IDs) != "[1 4]" {
+		t.Errorf("expected the auctions 1 and 4 but got %v", IDs)
+	}
+
+	if page.Next == "" {
+		t.Errorf("expected the next page to be kept")
+	}
+}
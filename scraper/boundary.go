@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+)
+
+// boundaryClass is the class eBay gives to the divider starting the "Results matching fewer words" section.
+const boundaryClass = "srp-river-answer--REWRITE_START"
+
+// boundaryWordings are the texts of the dividers which eBay inserts in the results river, before the results which do
+// not match the whole search, e.g. "Results matching fewer words" or "More items related to...".
+// They are lower-cased, and matched as substrings of the divider text.
+var boundaryWordings = []string{
+	// com, co.uk, ie, ca, com.au, com.sg, com.my, ph, in, com.hk
+	"results matching fewer words",
+	"more items related to",
+	// fr, ch, be-fr, ca-fr
+	"résultats correspondant à moins de mots",
+	"autres objets liés à",
+	"plus d'objets en rapport avec",
+	// de, at, ch
+	"ergebnisse mit weniger suchbegriffen",
+	"ergebnisse für weniger suchbegriffe",
+	"weitere artikel zu",
+	// es
+	"resultados que coinciden con menos palabras",
+	"más artículos relacionados con",
+	// it
+	"risultati corrispondenti a un numero inferiore di parole",
+	"altri oggetti correlati a",
+	// nl, be-nl
+	"resultaten met minder woorden",
+	"meer objecten gerelateerd aan",
+	// pl
+	"wyniki pasujące do mniejszej liczby słów",
+	"więcej przedmiotów związanych z",
+	// com.hk
+	"符合較少字詞的結果",
+	"更多相關物品",
+}
+
+// isBoundary returns whether the given divider element starts the section of the results which do not match the
+// whole search.
+func isBoundary(sel *goquery.Selection) bool {
+	if sel.HasClass(boundaryClass) {
+		return true
+	}
+
+	text := strings.ToLower(strings.Join(strings.Fields(sel.Text()), " "))
+	for _, w := range boundaryWordings {
+		if strings.Contains(text, w) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// boundaryIndex returns the number of items of the given list which come before the first "fewer words" divider of
+// the results river, or -1 if there is no such divider.
+func boundaryIndex(container *goquery.Selection, items *goquery.Selection, profile SelectorProfile) int {
+	var boundary *goquery.Selection
+	findFirst(container, profile.Boundary).EachWithBreak(func(i int, sel *goquery.Selection) bool {
+		if isBoundary(sel) {
+			boundary = sel
+			return false
+		}
+		return true
+	})
+
+	if boundary == nil {
+		return -1
+	}
+
+	// Find returns the elements in document order, so their indexes tell which items come before the divider.
+	all := container.Find("*")
+	boundaryPos := all.IndexOfSelection(boundary)
+
+	count := 0
+	items.Each(func(i int, sel *goquery.Selection) {
+		if all.IndexOfSelection(sel) < boundaryPos {
+			count++
+		}
+	})
+
+	return count
+}
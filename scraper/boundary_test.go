@@ -0,0 +1,80 @@
+package scraper
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBoundaryIndex(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "fewer_words", "*.html"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("could not find the fixtures: %v", err)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatalf("could not open fixture: %v", err)
+			}
+			defer f.Close()
+
+			doc, err := goquery.NewDocumentFromReader(f)
+			if err != nil {
+				t.Fatalf("could not create document: %v", err)
+			}
+
+			profile, container, items := findItems(doc, profilesFor(nil, ""))
+			if items.Length() != 3 {
+				t.Fatalf("expected 3 items but got %d", items.Length())
+			}
+
+			got := boundaryIndex(container, items, profile)
+			if got != 2 {
+				t.Errorf("expected the divider after 2 items but got %d", got)
+			}
+		})
+	}
+}
+
+func TestBoundaryIndexWithoutDivider(t *testing.T) {
+	html := `<div id="srp-river-results"><ul>
+<li class="srp-river-answer"><div>Save this search</div></li>
+<li class="s-item"><div class="s-item__info"><a class="s-item__link" href="https://www.ebay.com/itm/1"></a></div></li>
+</ul></div>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("could not create document: %v", err)
+	}
+
+	profile, container, items := findItems(doc, profilesFor(nil, ""))
+	if got := boundaryIndex(container, items, profile); got != -1 {
+		t.Errorf("expected no divider but got %d", got)
+	}
+}
+
+func TestIsBoundary(t *testing.T) {
+	tests := map[string]bool{
+		`<li class="srp-river-answer srp-river-answer--REWRITE_START"></li>`:                            true,
+		`<li class="srp-river-answer"><h3>Results matching  fewer words</h3></li>`:                      true,
+		`<li class="srp-river-answer"><h3>More items related to <b>duct tape</b></h3></li>`:             true,
+		`<li class="srp-river-answer"><h3>Weitere Artikel zu <b>Klebeband</b></h3></li>`:                true,
+		`<li class="srp-river-answer"><h3>Shop on eBay</h3></li>`:                                       false,
+		`<li class="srp-river-answer"><div>Save this search to get notified of new listings</div></li>`: false,
+	}
+
+	for html, exp := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			t.Fatalf("could not create document: %v", err)
+		}
+
+		if got := isBoundary(doc.Find("li")); got != exp {
+			t.Errorf("expected %v but got %v for %s", exp, got, html)
+		}
+	}
+}
@@ -5,16 +5,44 @@ import (
 	"github.com/goodsign/monday"
 	"strings"
 	"time"
+	// Embed the timezone database, as the eBay sites timezones must be available even on systems without it.
+	_ "time/tzdata"
 )
 
+// siteLocation returns the timezone of the eBay site with the given location domain.
+func siteLocation(locDomain string) (*time.Location, error) {
+	site, ok := LookupSite(locDomain)
+	if !ok {
+		return nil, fmt.Errorf("unknown timezone for loc domain: %s", locDomain)
+	}
+
+	return site.location()
+}
+
+// location returns the timezone of the site.
+func (s Site) location() (*time.Location, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("could not load location %s: %s", s.Timezone, err)
+	}
+
+	return loc, nil
+}
+
+// futureTolerance is how far in the future a listing date can be, compared to the scraping time, before it is
+// considered to be from the previous year.
+const futureTolerance = 24 * time.Hour
+
 // parseDate returns a time.Time from the given date as string. It handles multiple language formats determined from
 // the given listing URL.
 // For example, '.com' will be handled as US english, '.co.uk' as UK english, '.fr' as french.
+// As the dates have no year, the year is inferred from the given scraping time: it is the one which puts the date
+// closest to now without being in the future, e.g. Dec-31 23:50 scraped on January 1st is from the previous year.
 //
 // US example: Jun-26 06:21
 // UK example: 26-Jun 15:39
 // FR example: déc.-20 11:30
-func parseDate(str string, URL string) (time.Time, error) {
+func parseDate(str string, URL string, now time.Time) (time.Time, error) {
 	split := strings.Split(str, " ")
 	if len(split) < 2 {
 		return time.Time{}, fmt.Errorf("error while parsing date %s", str)
@@ -53,12 +81,14 @@ func parseDate(str string, URL string) (time.Time, error) {
 		return time.Time{}, fmt.Errorf("could not parse location domain from URL %s: %s", URL, err)
 	}
 
-	month = formatDirtyMonth(locDomain, month)
+	site, ok := LookupSite(locDomain)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unhandled loc domain: %s", locDomain)
+	}
 
-	year, _, _ := time.Now().Date()
-	fullDate := fmt.Sprintf("%s %s %d %s", day, month, year, hours)
+	month = site.formatMonth(month)
 
-	t, err := parseDateByLocDomain(fullDate, locDomain)
+	t, err := inferYear(day, month, hours, site, now)
 	if err != nil {
 		return time.Time{}, fmt.Errorf("error while parsing date %s: %v", str, err)
 	}
@@ -66,56 +96,50 @@ func parseDate(str string, URL string) (time.Time, error) {
 	return t, nil
 }
 
-// parseDateByLocDomain returns the time.Time from the given date as string and the given location domain.
-// The listing URL is used in order to detect and corresponding location (US, UK, FR, etc) and parse the date
-// accordingly.
-func parseDateByLocDomain(date string, locDomain string) (time.Time, error) {
-	var locale monday.Locale
-	switch locDomain {
-	case "com", "ca", "com.au", "com.sg", "com.my", "ph":
-		locale = monday.LocaleEnUS
-	case "co.uk", "ie":
-		locale = monday.LocaleEnGB
-	case "fr":
-		locale = monday.LocaleFrFR
-	case "de", "ch", "at":
-		locale = monday.LocaleDeDE
-	case "es":
-		locale = monday.LocaleEsES
-	case "it":
-		locale = monday.LocaleItIT
-	case "nl":
-		locale = monday.LocaleNlNL
-	case "pl":
-		locale = monday.LocalePlPL
-	default:
-		return time.Time{}, fmt.Errorf("unhandled loc domain: %s", locDomain)
-	}
+// inferYear returns the most recent date, from the year after now to the year before now, which is not more than
+// futureTolerance in the future.
+func inferYear(day string, month string, hours string, site Site, now time.Time) (time.Time, error) {
+	var firstErr error
+	for year := now.Year() + 1; year >= now.Year()-1; year-- {
+		fullDate := fmt.Sprintf("%s %s %d %s", day, month, year, hours)
+
+		t, err := parseDateBySite(fullDate, site)
+		if err != nil {
+			// e.g. Feb 29 on a non-leap year, the other years may be fine.
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
 
-	loc, err := time.LoadLocation("Local")
-	if err != nil {
-		return time.Time{}, fmt.Errorf("could not load location: %s", err)
+		if t.After(now.Add(futureTolerance)) {
+			continue
+		}
+
+		return t, nil
 	}
 
-	t, err := monday.ParseInLocation("2 Jan 2006 15:04", date, loc, locale)
-	if err != nil {
-		return time.Time{}, err
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no year puts the date before %v", now)
 	}
 
-	return t, nil
+	return time.Time{}, firstErr
 }
 
-// formatDirtyMonth manipulates the month for some specific location domains, and returns the new formatted month.
-func formatDirtyMonth(locDomain string, month string) string {
-	if locDomain == "fr" && len(month) > 1 && month[len(month)-1:] == "." {
-		month = firstN(month, 3)
+// parseDateBySite returns the time.Time from the given date as string, printed by the given eBay site. The date is
+// read in the locale and timezone of the site, so that the result does not depend on the timezone of the machine.
+func parseDateBySite(date string, site Site) (time.Time, error) {
+	loc, err := site.location()
+	if err != nil {
+		return time.Time{}, err
 	}
 
-	if locDomain == "de" && len(month) > 1 && month[len(month)-1:] == "." {
-		month = firstN(month, 3)
+	t, err := monday.ParseInLocation("2 Jan 2006 15:04", date, loc, site.Locale)
+	if err != nil {
+		return time.Time{}, err
 	}
 
-	return month
+	return t, nil
 }
 
 // firstN returns the first n characters of a string, and it correctly counts the unicode characters as 1.
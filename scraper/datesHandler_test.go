@@ -5,17 +5,39 @@ import (
 	"time"
 )
 
+// testNow is the scraping time of the date tests, late enough in the year for every test date to be in the same year.
+var testNow = time.Date(2021, 12, 31, 12, 0, 0, 0, time.UTC)
+
+// pinLocalTimezone sets the process timezone to one which is unrelated to the eBay sites, so that the tests prove the
+// dates do not depend on it. It returns a function restoring the previous timezone.
+func pinLocalTimezone() func() {
+	local := time.Local
+	time.Local = time.FixedZone("UTC+05:45", 5*3600+45*60)
+	return func() { time.Local = local }
+}
+
+// mustSiteLocation returns the timezone of the given eBay site, or fails the test.
+func mustSiteLocation(t *testing.T, locDomain string) *time.Location {
+	loc, err := siteLocation(locDomain)
+	if err != nil {
+		t.Fatalf("could not load the timezone of %s: %v", locDomain, err)
+	}
+	return loc
+}
+
 func TestParseDate(t *testing.T) {
+	defer pinLocalTimezone()()
+
 	t.Run("US format", func(t *testing.T) {
 		str := "Jun-26 06:21"
 		listingURL := "https://www.ebay.com/itm/393802831789?hash=item5bb07a57ad:g:q1MAAOSwCRthvdNa"
-		got, err := parseDate(str, listingURL)
+		got, err := parseDate(str, listingURL, testNow)
 		if err != nil {
 			t.Errorf("error while parsing date: %v", err)
 		}
 
-		exp := time.Date(time.Now().Year(), 6, 26, 6, 21, 00, 0, time.Local)
-		if exp != got {
+		exp := time.Date(testNow.Year(), 6, 26, 6, 21, 00, 0, mustSiteLocation(t, "com"))
+		if !exp.Equal(got) {
 			t.Errorf("expected %v but got %v", exp, got)
 		}
 	})
@@ -23,13 +45,13 @@ func TestParseDate(t *testing.T) {
 	t.Run("UK format", func(t *testing.T) {
 		str := "26-Jun 15:39"
 		listingURL := "https://www.ebay.co.uk/itm/393802831789?hash=item5bb07a57ad:g:q1MAAOSwCRthvdNa"
-		got, err := parseDate(str, listingURL)
+		got, err := parseDate(str, listingURL, testNow)
 		if err != nil {
 			t.Errorf("error while parsing date: %v", err)
 		}
 
-		exp := time.Date(time.Now().Year(), 6, 26, 15, 39, 00, 0, time.Local)
-		if exp != got {
+		exp := time.Date(testNow.Year(), 6, 26, 15, 39, 00, 0, mustSiteLocation(t, "co.uk"))
+		if !exp.Equal(got) {
 			t.Errorf("expected %v but got %v", exp, got)
 		}
 	})
@@ -37,25 +59,25 @@ func TestParseDate(t *testing.T) {
 	t.Run("FR format", func(t *testing.T) {
 		str := "déc.-20 11:30"
 		listingURL := "https://www.ebay.fr/itm/393802831789?hash=item5bb07a57ad:g:q1MAAOSwCRthvdNa"
-		got, err := parseDate(str, listingURL)
+		got, err := parseDate(str, listingURL, testNow)
 		if err != nil {
 			t.Errorf("error while parsing date: %v", err)
 		}
 
-		exp := time.Date(time.Now().Year(), 12, 20, 11, 30, 00, 0, time.Local)
-		if exp != got {
+		exp := time.Date(testNow.Year(), 12, 20, 11, 30, 00, 0, mustSiteLocation(t, "fr"))
+		if !exp.Equal(got) {
 			t.Errorf("expected %v but got %v", exp, got)
 		}
 
 		str = "avr.-17 21:08"
 		listingURL = "https://www.ebay.fr/itm/393802831789?hash=item5bb07a57ad:g:q1MAAOSwCRthvdNa"
-		got, err = parseDate(str, listingURL)
+		got, err = parseDate(str, listingURL, testNow)
 		if err != nil {
 			t.Errorf("error while parsing date: %v", err)
 		}
 
-		exp = time.Date(time.Now().Year(), 4, 17, 21, 8, 00, 0, time.Local)
-		if exp != got {
+		exp = time.Date(testNow.Year(), 4, 17, 21, 8, 00, 0, mustSiteLocation(t, "fr"))
+		if !exp.Equal(got) {
 			t.Errorf("expected %v but got %v", exp, got)
 		}
 	})
@@ -63,18 +85,95 @@ func TestParseDate(t *testing.T) {
 	t.Run("DE format", func(t *testing.T) {
 		str := "17. Nov. 04:09"
 		listingURL := "https://www.ebay.de/itm/393802831789?hash=item5bb07a57ad:g:q1MAAOSwCRthvdNa"
-		got, err := parseDate(str, listingURL)
+		got, err := parseDate(str, listingURL, testNow)
 		if err != nil {
 			t.Errorf("error while parsing date: %v", err)
 		}
 
-		exp := time.Date(time.Now().Year(), 11, 17, 4, 9, 00, 0, time.Local)
-		if exp != got {
+		exp := time.Date(testNow.Year(), 11, 17, 4, 9, 00, 0, mustSiteLocation(t, "de"))
+		if !exp.Equal(got) {
 			t.Errorf("expected %v but got %v", exp, got)
 		}
 	})
 }
 
+func TestParseDateInstant(t *testing.T) {
+	defer pinLocalTimezone()()
+
+	// The same listing time printed by different sites gives different instants.
+	got, err := parseDate("Jan-15 10:00", "https://www.ebay.com/itm/1", testNow)
+	if err != nil {
+		t.Fatalf("error while parsing date: %v", err)
+	}
+
+	// 10:00 in Los Angeles in winter is 18:00 UTC.
+	exp := time.Date(testNow.Year(), 1, 15, 18, 0, 0, 0, time.UTC)
+	if !exp.Equal(got) {
+		t.Errorf("expected %v but got %v", exp, got)
+	}
+
+	got, err = parseDate("15-Jan 10:00", "https://www.ebay.co.uk/itm/1", testNow)
+	if err != nil {
+		t.Fatalf("error while parsing date: %v", err)
+	}
+
+	exp = time.Date(testNow.Year(), 1, 15, 10, 0, 0, 0, time.UTC)
+	if !exp.Equal(got) {
+		t.Errorf("expected %v but got %v", exp, got)
+	}
+}
+
+func TestParseDateYear(t *testing.T) {
+	defer pinLocalTimezone()()
+
+	la := mustSiteLocation(t, "com")
+
+	tests := []struct {
+		name string
+		str  string
+		now  time.Time
+		exp  time.Time
+	}{
+		{
+			name: "Listed on Dec 31, scraped on Jan 1",
+			str:  "Dec-31 23:50",
+			now:  time.Date(2022, 1, 1, 0, 10, 0, 0, la),
+			exp:  time.Date(2021, 12, 31, 23, 50, 0, 0, la),
+		},
+		{
+			name: "Listed on Jan 1, scraped on Dec 31 in an earlier timezone",
+			str:  "Jan-01 00:05",
+			now:  time.Date(2021, 12, 31, 23, 55, 0, 0, la),
+			exp:  time.Date(2022, 1, 1, 0, 5, 0, 0, la),
+		},
+		{
+			name: "Listed in the past months",
+			str:  "Jun-26 06:21",
+			now:  time.Date(2022, 3, 10, 12, 0, 0, 0, la),
+			exp:  time.Date(2021, 6, 26, 6, 21, 0, 0, la),
+		},
+		{
+			name: "Listed on Feb 29, scraped the next year",
+			str:  "Feb-29 10:00",
+			now:  time.Date(2021, 1, 5, 12, 0, 0, 0, la),
+			exp:  time.Date(2020, 2, 29, 10, 0, 0, 0, la),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDate(tt.str, "https://www.ebay.com/itm/1", tt.now)
+			if err != nil {
+				t.Fatalf("error while parsing date: %v", err)
+			}
+
+			if !tt.exp.Equal(got) {
+				t.Errorf("expected %v but got %v", tt.exp, got)
+			}
+		})
+	}
+}
+
 func TestFirstN(t *testing.T) {
 	t.Run("Unicode", func(t *testing.T) {
 		got := firstN("世界 Hello", 1)
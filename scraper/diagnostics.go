@@ -0,0 +1,96 @@
+package scraper
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LayoutBreak describes a search results page which looks like it could not be scraped correctly, most likely
+// because eBay changed its markup.
+type LayoutBreak struct {
+	URL    string
+	Domain string
+	Reason string
+	// HTML is the content of the offending page, to be saved for debugging.
+	HTML string
+}
+
+// pageStats keeps track of what happened while parsing the items of a search results page.
+type pageStats struct {
+	items       int
+	parsed      int
+	dateErrors  int
+	emptyTitles int
+	emptyPrices int
+}
+
+// add records the given parsed listing.
+func (p *pageStats) add(listing Listing) {
+	p.parsed++
+	if listing.Title == "" {
+		p.emptyTitles++
+	}
+	if listing.Price == "" {
+		p.emptyPrices++
+	}
+}
+
+// detectLayoutBreak returns a non-empty reason when the given page stats look like the selector profiles do not
+// match the page anymore.
+// resultsCount is the number of results announced by the page, or -1 if unknown.
+func detectLayoutBreak(stats pageStats, resultsCount int) string {
+	if stats.items == 0 && resultsCount > 0 {
+		return fmt.Sprintf("the page announces %d results but no items were found", resultsCount)
+	}
+
+	if stats.dateErrors > 0 && stats.parsed == 0 {
+		return "the listing dates could not be parsed"
+	}
+
+	if stats.parsed == 0 {
+		return ""
+	}
+
+	if stats.emptyTitles == stats.parsed {
+		return fmt.Sprintf("all the %d parsed listings have an empty title", stats.parsed)
+	}
+
+	if stats.emptyPrices == stats.parsed {
+		return fmt.Sprintf("all the %d parsed listings have an empty price", stats.parsed)
+	}
+
+	return ""
+}
+
+var digitsRegexp = regexp.MustCompile(`\d[\d,.\s\x{00a0}\x{202f}']*`)
+
+// parseResultsCount returns the number of results announced by the given search results page, e.g. "1,234 results
+// for duct tape". It returns -1 if the count cannot be found.
+func parseResultsCount(doc *goquery.Document, profile SelectorProfile) int {
+	text := textOf(doc.Selection, profile.Count)
+	if text == "" {
+		return -1
+	}
+
+	match := digitsRegexp.FindString(text)
+	if match == "" {
+		return -1
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, match)
+
+	count, err := strconv.Atoi(digits)
+	if err != nil {
+		return -1
+	}
+
+	return count
+}
@@ -0,0 +1,56 @@
+package scraper
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+	"testing"
+)
+
+func TestDetectLayoutBreak(t *testing.T) {
+	tests := []struct {
+		name         string
+		stats        pageStats
+		resultsCount int
+		broken       bool
+	}{
+		{"Results but no items", pageStats{}, 42, true},
+		{"No results", pageStats{}, 0, false},
+		{"Unknown count and no items", pageStats{}, -1, false},
+		{"All dates failing", pageStats{items: 50, dateErrors: 1}, 50, true},
+		{"Empty titles", pageStats{items: 50, parsed: 2, emptyTitles: 2}, 50, true},
+		{"Empty prices", pageStats{items: 50, parsed: 2, emptyPrices: 2}, 50, true},
+		{"Some empty prices", pageStats{items: 50, parsed: 2, emptyPrices: 1}, 50, false},
+		{"Stopped on a known listing", pageStats{items: 50}, 50, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectLayoutBreak(tt.stats, tt.resultsCount)
+			if (got != "") != tt.broken {
+				t.Errorf("expected broken=%v but got reason %q", tt.broken, got)
+			}
+		})
+	}
+}
+
+func TestParseResultsCount(t *testing.T) {
+	tests := map[string]int{
+		`<h1 class="srp-controls__count-heading"><span class="BOLD">1,234</span> results for <span class="BOLD">duct tape</span></h1>`: 1234,
+		`<h1 class="srp-controls__count-heading"><span class="BOLD">12.345</span> Ergebnisse für <span class="BOLD">tape</span></h1>`:  12345,
+		`<h1 class="srp-controls__count-heading"><span class="BOLD">1 234</span> résultats pour <span class="BOLD">tape</span></h1>`:   1234,
+		`<h1 class="srp-controls__count-heading">No exact matches found</h1>`:                                                          -1,
+		`<div>nothing</div>`: -1,
+	}
+
+	for html, exp := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			t.Fatalf("could not create document: %v", err)
+		}
+
+		got := parseResultsCount(doc, DefaultProfile())
+		if got != exp {
+			t.Errorf("expected %d but got %d for %s", exp, got, html)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package scraper
+
+import (
+	"ebay-watchdog/web"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// itemPageSelectors are the CSS selectors used to read the item pages, from the most recent markup to the oldest one.
+var itemPageSelectors = struct {
+	Seller          []string
+	FeedbackScore   []string
+	FeedbackPercent []string
+	SpecificsRow    []string
+	SpecificsLabel  []string
+	SpecificsValue  []string
+	Returns         []string
+	DescriptionURL  []string
+}{
+	Seller: []string{
+		".x-sellercard-atf__info__about-seller a span",
+		".ux-seller-section__item--seller a span",
+		"#RightSummaryPanel .mbg-nw",
+	},
+	FeedbackScore: []string{
+		".x-sellercard-atf__about-seller .ux-textspans--SECONDARY",
+		".ux-seller-section__item--seller .ux-textspans--PSEUDOLINK",
+		"#RightSummaryPanel .mbg-l a",
+	},
+	FeedbackPercent: []string{
+		".x-sellercard-atf__data-item .ux-textspans",
+		".ux-seller-section__item--seller + .ux-seller-section__item",
+		"#si-fb",
+	},
+	SpecificsRow: []string{
+		".ux-layout-section-evo__col",
+		".ux-layout-section__row .ux-labels-values",
+	},
+	SpecificsLabel: []string{".ux-labels-values__labels"},
+	SpecificsValue: []string{".ux-labels-values__values"},
+	Returns: []string{
+		".x-returns-minview .ux-labels-values__values",
+		"[data-testid='x-returns-minview'] .ux-labels-values__values",
+		"#vi-ret-accrd-txt",
+	},
+	DescriptionURL: []string{"iframe#desc_ifr"},
+}
+
+// ItemDetails are the details of a listing which can only be found on its item page.
+type ItemDetails struct {
+	Seller          string
+	FeedbackScore   int
+	FeedbackPercent string
+	Specifics       map[string]string
+	ReturnsPolicy   string
+	// DescriptionURL is the URL of the frame holding the full description.
+	DescriptionURL string
+}
+
+// Enricher fetches the item page of new listings, in order to add the details which are missing from the search
+// results: seller, item specifics, description and returns policy.
+type Enricher struct {
+	// Concurrency is the number of item pages fetched at the same time.
+	Concurrency int
+	// Delay is the minimum period between two item page requests.
+	Delay time.Duration
+	// Description also fetches the full description of the listings, which is one more request per listing.
+	Description bool
+
+	get func(URL string) (*goquery.Document, error)
+	// blocked returns whether the site of the given listing URL blocked a request.
+	blocked func(URL string) bool
+}
+
+func NewEnricher(concurrency int, delay time.Duration, description bool, client *web.Client) *Enricher {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &Enricher{
+		Concurrency: concurrency,
+		Delay:       delay,
+		Description: description,
+		get:         client.Get,
+		blocked: func(URL string) bool {
+			return client.Blocked(URL) != nil
+		},
+	}
+}
+
+// Enrich fetches the item page of each given listing, and returns the listings with their extra fields set.
+// A listing whose item page cannot be fetched, or whose site blocked a request, is returned as is.
+func (e *Enricher) Enrich(listings []Listing) []Listing {
+	if len(listings) == 0 {
+		return listings
+	}
+
+	log.Printf("Enriching %d listings\n", len(listings))
+
+	res := make([]Listing, len(listings))
+	copy(res, listings)
+
+	// All the workers share the same rate limit.
+	var limiter <-chan time.Time
+	if e.Delay > 0 {
+		ticker := time.NewTicker(e.Delay)
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+	wait := func() {
+		if limiter != nil {
+			<-limiter
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < e.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if e.blocked(res[i].URL) {
+					log.Printf("Not enriching listing %s, its site blocked a request\n", res[i].ID)
+					continue
+				}
+
+				err := e.enrich(&res[i], wait)
+				if err != nil {
+					log.Printf("could not enrich listing %s: %v\n", res[i].ID, err)
+				}
+			}
+		}()
+	}
+
+	for i := range res {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return res
+}
+
+// enrich fetches the item page of the given listing and sets its extra fields.
+func (e *Enricher) enrich(listing *Listing, wait func()) error {
+	wait()
+	doc, err := e.get(listing.URL)
+	if err != nil {
+		return fmt.Errorf("could not fetch item page %s: %w", listing.URL, err)
+	}
+
+	details := parseItemPage(doc)
+	listing.Enriched = true
+	// Keep the seller found in the search results if the item page does not show it.
+	if details.Seller != "" {
+		listing.Seller = details.Seller
+		listing.SellerFeedbackScore = details.FeedbackScore
+		listing.SellerFeedbackPercent = details.FeedbackPercent
+	}
+	listing.Specifics = details.Specifics
+	listing.ReturnsPolicy = details.ReturnsPolicy
+
+	if !e.Description || details.DescriptionURL == "" {
+		return nil
+	}
+
+	wait()
+	desc, err := e.get(details.DescriptionURL)
+	if err != nil {
+		return fmt.Errorf("could not fetch description %s: %v", details.DescriptionURL, err)
+	}
+
+	if desc != nil {
+		listing.Description = cleanText(desc.Find("body").Text())
+	}
+
+	return nil
+}
+
+var feedbackScoreRegexp = regexp.MustCompile(`\d[\d,.\s]*`)
+
+// parseItemPage returns the details found on the given item page.
+func parseItemPage(doc *goquery.Document) ItemDetails {
+	sel := doc.Selection
+	details := ItemDetails{
+		Seller:          textOf(sel, itemPageSelectors.Seller),
+		FeedbackPercent: cleanText(textOf(sel, itemPageSelectors.FeedbackPercent)),
+		Specifics:       make(map[string]string),
+		ReturnsPolicy:   cleanText(textOf(sel, itemPageSelectors.Returns)),
+	}
+
+	score := feedbackScoreRegexp.FindString(textOf(sel, itemPageSelectors.FeedbackScore))
+	score = strings.NewReplacer(",", "", ".", "", " ", "").Replace(strings.TrimSpace(score))
+	if n, err := strconv.Atoi(score); err == nil {
+		details.FeedbackScore = n
+	}
+
+	findFirst(sel, itemPageSelectors.SpecificsRow).Each(func(i int, row *goquery.Selection) {
+		label := strings.TrimSuffix(cleanText(textOf(row, itemPageSelectors.SpecificsLabel)), ":")
+		value := cleanText(textOf(row, itemPageSelectors.SpecificsValue))
+		if label != "" && value != "" {
+			details.Specifics[label] = value
+		}
+	})
+
+	// Older item pages have a table of specifics.
+	if len(details.Specifics) == 0 {
+		sel.Find(".itemAttr td.attrLabels").Each(func(i int, label *goquery.Selection) {
+			key := strings.TrimSuffix(cleanText(label.Text()), ":")
+			value := cleanText(label.Next().Text())
+			if key != "" && value != "" {
+				details.Specifics[key] = value
+			}
+		})
+	}
+
+	details.DescriptionURL, _ = attrOf(sel, itemPageSelectors.DescriptionURL, "src")
+
+	return details
+}
+
+// cleanText collapses the whitespaces of the given text.
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,135 @@
+package scraper
+
+import (
+	"ebay-watchdog/web"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestParseItemPage(t *testing.T) {
+	t.Run("Current layout", func(t *testing.T) {
+		got := parseItemPage(loadFixture(t, "item", "evo.html"))
+		exp := ItemDetails{
+			Seller:          "sportsdeals_usa",
+			FeedbackScore:   12345,
+			FeedbackPercent: "99.8% positive",
+			Specifics: map[string]string{
+				"Condition":    "New with box",
+				"Brand":        "PUMA",
+				"US Shoe Size": "5",
+			},
+			ReturnsPolicy:  "30 days returns. Buyer pays for return shipping.",
+			DescriptionURL: "https://vi.vipr.ebaydesc.com/ws/eBayISAPI.dll?ViewItemDescV4&item=402943017690",
+		}
+
+		if !reflect.DeepEqual(exp, got) {
+			t.Errorf("expected %+v but got %+v", exp, got)
+		}
+	})
+
+	t.Run("Legacy layout", func(t *testing.T) {
+		got := parseItemPage(loadFixture(t, "item", "legacy.html"))
+		exp := ItemDetails{
+			Seller:          "tape_shop_uk",
+			FeedbackScore:   871,
+			FeedbackPercent: "100% Positive feedback",
+			Specifics: map[string]string{
+				"Condition": "New",
+				"Brand":     "3M",
+				"Colour":    "Silver",
+			},
+			ReturnsPolicy: "No returns accepted",
+		}
+
+		if !reflect.DeepEqual(exp, got) {
+			t.Errorf("expected %+v but got %+v", exp, got)
+		}
+	})
+}
+
+func TestEnrich(t *testing.T) {
+	fixtures := map[string]string{
+		"https://www.ebay.com/itm/402943017690":                                          "evo.html",
+		"https://www.ebay.co.uk/itm/393802831789":                                        "legacy.html",
+		"https://vi.vipr.ebaydesc.com/ws/eBayISAPI.dll?ViewItemDescV4&item=402943017690": "description.html",
+	}
+
+	var mu sync.Mutex
+	requested := make(map[string]int)
+
+	e := NewEnricher(2, 0, true, web.DefaultClient)
+	e.get = func(URL string) (*goquery.Document, error) {
+		mu.Lock()
+		requested[URL]++
+		mu.Unlock()
+
+		file, ok := fixtures[URL]
+		if !ok {
+			return nil, fmt.Errorf("not found")
+		}
+		return loadFixture(t, "item", file), nil
+	}
+
+	listings := []Listing{
+		{ID: "402943017690", URL: "https://www.ebay.com/itm/402943017690"},
+		{ID: "393802831789", URL: "https://www.ebay.co.uk/itm/393802831789"},
+		{ID: "1", URL: "https://www.ebay.com/itm/1", Title: "Deleted"},
+	}
+
+	got := e.Enrich(listings)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 listings but got %d", len(got))
+	}
+
+	if got[0].Seller != "sportsdeals_usa" || got[0].Specifics["Brand"] != "PUMA" {
+		t.Errorf("unexpected enriched listing %+v", got[0])
+	}
+
+	if got[0].Description != "Brand new Puma training ball. Ships within 24 hours." {
+		t.Errorf("unexpected description %q", got[0].Description)
+	}
+
+	if got[1].Seller != "tape_shop_uk" || got[1].ReturnsPolicy != "No returns accepted" || got[1].Description != "" {
+		t.Errorf("unexpected enriched listing %+v", got[1])
+	}
+
+	if !reflect.DeepEqual(listings[2], got[2]) {
+		t.Errorf("expected the listing without item page to be unchanged, got %+v", got[2])
+	}
+
+	if listings[0].Seller != "" {
+		t.Errorf("expected the given listings not to be modified")
+	}
+
+	for URL, n := range requested {
+		if n != 1 {
+			t.Errorf("expected %s to be requested once, got %d", URL, n)
+		}
+	}
+
+	t.Run("Blocked site", func(t *testing.T) {
+		client := web.NewClient(web.ClientOptions{})
+		client.Block("https://www.ebay.co.uk/sch/i.html?_nkw=tape", &web.BlockedError{StatusCode: 429})
+
+		e := NewEnricher(2, 0, false, client)
+		requested := make(map[string]int)
+		e.get = func(URL string) (*goquery.Document, error) {
+			mu.Lock()
+			requested[URL]++
+			mu.Unlock()
+
+			return loadFixture(t, "item", fixtures[URL]), nil
+		}
+
+		got := e.Enrich(listings[:2])
+		if !got[0].Enriched || got[1].Enriched {
+			t.Errorf("expected only the listing of the site which is not blocked to be enriched, got %+v", got)
+		}
+		if requested["https://www.ebay.co.uk/itm/393802831789"] != 0 {
+			t.Errorf("expected the item page of the blocked site not to be requested")
+		}
+	})
+}
@@ -0,0 +1,259 @@
+package scraper
+
+import (
+	"ebay-watchdog/web"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTMLSource scrapes the listings from the eBay search results pages.
+type HTMLSource struct {
+	Profiles []SelectorProfile
+	Client   *web.Client
+	// Now returns the scraping time, used to infer the year of the listing dates.
+	Now func() time.Time
+	// OnLayoutBreak, when set, is called whenever a search results page looks like it could not be scraped
+	// correctly.
+	OnLayoutBreak func(LayoutBreak)
+}
+
+func NewHTMLSource(profiles []SelectorProfile, client *web.Client) *HTMLSource {
+	return &HTMLSource{
+		Profiles: profiles,
+		Client:   client,
+		Now:      time.Now,
+	}
+}
+
+// Fetch implements Source.
+func (h *HTMLSource) Fetch(searchURL SearchURL, URL string, domain string) (Page, error) {
+	doc, err := h.Client.Get(URL)
+	if err != nil {
+		return Page{}, fmt.Errorf("could not make request to search URL page %s: %w", URL, err)
+	}
+
+	return h.parsePage(doc, searchURL, URL, domain), nil
+}
+
+// parsePage returns the listings of the given search results page.
+// The listings stop at the first one which has a date that cannot be parsed, as the following ones could not be
+// compared to the last scraped listing. In ModeEnding, the listings are sorted by end time instead: the ones without
+// a time left, e.g. Buy It Now listings, are only skipped.
+func (h *HTMLSource) parsePage(doc *goquery.Document, searchURL SearchURL, URL string, domain string) Page {
+	stats := pageStats{}
+
+	profile, container, itemInfoList := findItems(doc, profilesFor(h.Profiles, domain))
+	stats.items = itemInfoList.Length()
+	boundary := boundaryIndex(container, itemInfoList, profile)
+
+	if profile.Version != DefaultProfile().Version {
+		log.Printf("Using selector profile %s for URL %s\n", profile.Version, URL)
+	}
+
+	if stats.items == 0 {
+		log.Printf("received zero items for URL %s\n", URL)
+	}
+
+	now := h.Now()
+	var listings []Listing
+	truncated := false
+	itemInfoList.EachWithBreak(func(i int, sel *goquery.Selection) bool {
+		fewerWords := boundary >= 0 && i >= boundary
+		if fewerWords && !searchURL.FlagFewerWords {
+			log.Println("Stop - Reached the results matching fewer words!")
+			truncated = true
+			return false
+		}
+
+		var listing *Listing
+		var err error
+		if searchURL.Mode == ModeEnding {
+			listing = extractFields(sel, profile, now)
+			if listing != nil && listing.EndsAt.IsZero() && !listing.Sponsored {
+				log.Printf("Skipping listing %s, its time left could not be parsed\n", listing.ID)
+				stats.add(*listing)
+				return true
+			}
+		} else {
+			listing, err = extractItem(sel, profile, now)
+		}
+		if err != nil {
+			log.Println("error while parsing item", err)
+			stats.dateErrors++
+			truncated = true
+			// Keep going, only to gather the page stats.
+			return true
+		}
+
+		if listing == nil {
+			return true
+		}
+
+		stats.add(*listing)
+		if !truncated {
+			listing.FewerWords = fewerWords
+			listings = append(listings, *listing)
+		}
+
+		return true
+	})
+
+	h.checkLayout(doc, profile, stats, URL, domain)
+
+	page := Page{Listings: listings}
+	// The next pages are not worth reading once the listings were truncated.
+	if !truncated {
+		page.Next = nextPageURL(doc, profile, URL)
+	}
+
+	return page
+}
+
+// nextPageURL returns the absolute URL of the next results page, or an empty string if there is none.
+func nextPageURL(doc *goquery.Document, profile SelectorProfile, URL string) string {
+	href, ok := attrOf(doc.Selection, profile.Next, "href")
+	if !ok || href == "" {
+		return ""
+	}
+
+	base, err := url.Parse(URL)
+	if err != nil {
+		return ""
+	}
+
+	next, err := base.Parse(href)
+	if err != nil || next.String() == URL {
+		return ""
+	}
+
+	return next.String()
+}
+
+// checkLayout looks for signs of an eBay markup change in the given parsed page, and reports them through
+// OnLayoutBreak.
+func (h *HTMLSource) checkLayout(doc *goquery.Document, profile SelectorProfile, stats pageStats, URL string, domain string) {
+	reason := detectLayoutBreak(stats, parseResultsCount(doc, profile))
+	if reason == "" {
+		return
+	}
+
+	log.Printf("Possible layout break for URL %s: %s\n", URL, reason)
+	if h.OnLayoutBreak == nil {
+		return
+	}
+
+	html, err := doc.Html()
+	if err != nil {
+		log.Println("could not render the page HTML", err)
+	}
+
+	h.OnLayoutBreak(LayoutBreak{
+		URL:    URL,
+		Domain: domain,
+		Reason: reason,
+		HTML:   html,
+	})
+}
+
+// findItems returns the listing elements of the given search results page, using the first selector profile which
+// finds at least one item. It also returns the profile that was used, so that the items are parsed with the same one,
+// and the results container.
+func findItems(doc *goquery.Document, profiles []SelectorProfile) (SelectorProfile, *goquery.Selection, *goquery.Selection) {
+	for _, profile := range profiles {
+		riverResults := findFirst(doc.Selection, profile.Container)
+		if riverResults.Length() == 0 {
+			continue
+		}
+
+		items := findFirst(riverResults, profile.Item)
+		if items.Length() > 0 {
+			return profile, riverResults, items
+		}
+	}
+
+	empty := doc.Selection.Slice(0, 0)
+	return DefaultProfile(), empty, empty
+}
+
+// extractItem returns the listing from the given item element, scraped at the given time. It returns nil if the
+// element is not a listing, and an error if the listing date cannot be parsed. Sponsored listings are returned without
+// a date instead, as their date is never compared to the last scraped listing.
+func extractItem(sel *goquery.Selection, profile SelectorProfile, now time.Time) (*Listing, error) {
+	listing := extractFields(sel, profile, now)
+	if listing == nil {
+		return nil, nil
+	}
+
+	date := textOf(sel, profile.Date)
+	t, err := parseDate(date, listing.URL, now)
+	if err != nil && listing.Sponsored {
+		return listing, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse date %s: %v", date, err)
+	}
+
+	listing.Date = t
+
+	return listing, nil
+}
+
+// extractFields returns the listing from the given item element, scraped at the given time, without its publication
+// date. The end of the listing and its bids are only set for the auctions. It returns nil if the element is not a
+// listing.
+func extractFields(sel *goquery.Selection, profile SelectorProfile, now time.Time) *Listing {
+	itemSel := sel.Children()
+	if len(itemSel.Nodes) < 3 {
+		return nil
+	}
+
+	rawURL, exists := attrOf(sel, profile.Link, "href")
+	if !exists {
+		return nil
+	}
+
+	// Listing URLs with amdata generate different URLs for the same listings
+	// Removing the amdata allows us to determine if a listing has already been scraped or not.
+	URL := strings.Split(rawURL, "&amdata")[0]
+
+	split := strings.Split(URL, "/")
+
+	// The image is not part of the item info, but of the item wrapper.
+	image, _ := attrOf(sel.Parent(), profile.Image, "src")
+
+	listing := &Listing{
+		URL:       URL,
+		Title:     textOf(sel, profile.Title),
+		Subtitle:  textOf(sel, profile.Subtitle),
+		Price:     textOf(sel, profile.Price),
+		ID:        split[len(split)-1],
+		Image:     image,
+		Condition: textOf(sel, profile.Condition),
+		Shipping:  textOf(sel, profile.Shipping),
+		Location:  textOf(sel, profile.Location),
+		Seller:    parseSeller(textOf(sel, profile.Seller)),
+		Sponsored: isSponsored(sel, profile),
+	}
+
+	if timeLeft, err := parseTimeLeft(textOf(sel, profile.TimeLeft)); err == nil {
+		listing.EndsAt = now.Add(timeLeft)
+		listing.Bids = parseBids(textOf(sel, profile.Bids))
+	}
+
+	return listing
+}
+
+// parseSeller returns the seller username from the given seller information, e.g. tape_seller for
+// tape_seller (1,234) 99.5%.
+func parseSeller(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[0]
+}
@@ -0,0 +1,76 @@
+package scraper
+
+import (
+	"ebay-watchdog/web"
+	"github.com/PuerkitoBio/goquery"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadFixture(t *testing.T, path ...string) *goquery.Document {
+	f, err := os.Open(filepath.Join(append([]string{"testdata"}, path...)...))
+	if err != nil {
+		t.Fatalf("could not open fixture: %v", err)
+	}
+	defer f.Close()
+
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatalf("could not create document: %v", err)
+	}
+
+	return doc
+}
+
+func TestParsePageFewerWords(t *testing.T) {
+	URL := "https://www.ebay.com/sch/i.html?_nkw=duct+tape&_sop=10"
+
+	t.Run("Stop at the divider", func(t *testing.T) {
+		doc := loadFixture(t, "fewer_words", "com.html")
+		page := NewHTMLSource(nil, web.DefaultClient).parsePage(doc, SearchURL{URL: URL}, URL, "com")
+		if len(page.Listings) != 2 {
+			t.Fatalf("expected 2 listings but got %d", len(page.Listings))
+		}
+
+		for _, l := range page.Listings {
+			if l.FewerWords {
+				t.Errorf("expected listing %s not to be flagged", l.ID)
+			}
+		}
+	})
+
+	t.Run("Flag the listings below the divider", func(t *testing.T) {
+		doc := loadFixture(t, "fewer_words", "com.html")
+		page := NewHTMLSource(nil, web.DefaultClient).parsePage(doc, SearchURL{URL: URL, FlagFewerWords: true}, URL, "com")
+		if len(page.Listings) != 3 {
+			t.Fatalf("expected 3 listings but got %d", len(page.Listings))
+		}
+
+		if page.Listings[1].FewerWords || !page.Listings[2].FewerWords {
+			t.Errorf("expected only the last listing to be flagged")
+		}
+	})
+}
+
+func TestParsePageLayoutBreak(t *testing.T) {
+	URL := "https://www.ebay.com/sch/i.html?_nkw=duct+tape&_sop=10"
+	doc := loadFixture(t, "fewer_words", "com.html")
+
+	// A profile whose item selector matches nothing, while the page announces results.
+	source := NewHTMLSource(nil, web.DefaultClient)
+	var got []LayoutBreak
+	source.OnLayoutBreak = func(b LayoutBreak) {
+		got = append(got, b)
+	}
+
+	doc.Find("div.s-item__info").RemoveClass("s-item__info").AddClass("s-card__info")
+	page := source.parsePage(doc, SearchURL{URL: URL}, URL, "com")
+	if len(page.Listings) != 0 {
+		t.Errorf("expected zero listings but got %d", len(page.Listings))
+	}
+
+	if len(got) != 1 || got[0].Domain != "com" || got[0].HTML == "" {
+		t.Errorf("expected one layout break with the page HTML, got %+v", got)
+	}
+}
@@ -0,0 +1,151 @@
+package scraper
+
+import (
+	"ebay-watchdog/web"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// itemStateSelectors are the CSS selectors used to read the state of an item page, from the most recent markup to the
+// oldest one.
+var itemStateSelectors = struct {
+	Title     []string
+	Price     []string
+	Bids      []string
+	Available []string
+	Status    []string
+}{
+	Title:     []string{".x-item-title__mainTitle", "#itemTitle"},
+	Price:     []string{".x-price-primary", "#prcIsum", "#prcIsum_bidPrice", "#mm-saleDscPrc"},
+	Bids:      []string{".x-bid-count", "[data-testid='x-bid-count']", "#qty-test", "#vi-VR-bid-lnk span"},
+	Available: []string{".x-quantity__availability", "#qtySubTxt"},
+	Status: []string{
+		"[data-testid='d-statusmessage']",
+		".d-statusmessage",
+		".x-alert-banner",
+		"#msgPanel",
+	},
+}
+
+// ItemState is what can change on the item page of a listing.
+type ItemState struct {
+	Title string
+	Price string
+	Bids  int
+	// Available is the quantity still available, -1 when the page does not tell it.
+	Available         int
+	BestOfferAccepted bool
+	Ended             bool
+}
+
+// endedWordings are the status messages of the ended listings, in the languages of the eBay sites.
+var endedWordings = []string{
+	"ended", "beendet", "terminé", "terminée", "terminata", "terminato", "finalizado", "finalizada", "beëindigd",
+	"afgelopen", "zakończon",
+}
+
+// bestOfferAcceptedWordings are the status messages of the listings sold with a best offer.
+var bestOfferAcceptedWordings = []string{
+	"best offer accepted", "preisvorschlag angenommen", "offre directe acceptée", "proposta d'acquisto accettata",
+	"oferta aceptada", "bod geaccepteerd", "propozycja zaakceptowana",
+}
+
+// lastOneWordings are the quantities of the listings with a single item left.
+var lastOneWordings = []string{"last one", "letzter artikel", "dernier", "ultimo", "último", "laatste", "ostatni"}
+
+var availableRegexp = regexp.MustCompile(`\d[\d,.\s]*`)
+
+// FetchItemState fetches the item page with the given URL using the given client, and returns its state.
+func FetchItemState(client *web.Client, URL string) (ItemState, error) {
+	doc, err := client.Get(URL)
+	if err != nil {
+		return ItemState{}, fmt.Errorf("could not fetch item page %s: %w", URL, err)
+	}
+
+	return parseItemState(doc), nil
+}
+
+// parseItemState returns the state found on the given item page.
+func parseItemState(doc *goquery.Document) ItemState {
+	sel := doc.Selection
+	status := strings.ToLower(cleanText(textOf(sel, itemStateSelectors.Status)))
+
+	state := ItemState{
+		Title:             cleanText(textOf(sel, itemStateSelectors.Title)),
+		Price:             cleanText(textOf(sel, itemStateSelectors.Price)),
+		Bids:              parseBids(textOf(sel, itemStateSelectors.Bids)),
+		Available:         parseAvailable(textOf(sel, itemStateSelectors.Available)),
+		BestOfferAccepted: containsAny(status, bestOfferAcceptedWordings),
+		Ended:             containsAny(status, endedWordings),
+	}
+
+	return state
+}
+
+// parseAvailable returns the quantity available from the given text, e.g. 3 available, More than 10 available, Last
+// one. It returns -1 when it is unknown.
+func parseAvailable(str string) int {
+	str = strings.ToLower(str)
+	if containsAny(str, lastOneWordings) {
+		return 1
+	}
+
+	digits := strings.NewReplacer(",", "", ".", "", " ", "").Replace(strings.TrimSpace(availableRegexp.FindString(str)))
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
+
+// containsAny returns whether the given text contains one of the given wordings.
+func containsAny(text string, wordings []string) bool {
+	for _, w := range wordings {
+		if strings.Contains(text, w) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseItemRef returns the item ID and the item page URL of the given item reference: either an item ID, which is
+// looked for on ebay.com, or the URL of an item page on an eBay site.
+func ParseItemRef(ref string) (string, string, error) {
+	ref = strings.TrimSpace(ref)
+	if isDigits(ref) {
+		return ref, ItemURL(ref, "com"), nil
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil || u.Host == "" {
+		return "", "", fmt.Errorf("%q is neither an item ID nor an item URL", ref)
+	}
+
+	site, ok := LookupSiteByHost(u.Host)
+	if !ok {
+		return "", "", fmt.Errorf("%q is not on a supported eBay site", ref)
+	}
+
+	ID := Listing{URL: ref}.ItemID()
+	if !isDigits(ID) {
+		return "", "", fmt.Errorf("could not find the item ID of %q", ref)
+	}
+
+	return ID, ItemURL(ID, site.Domain), nil
+}
+
+// ItemURL returns the URL of the item page with the given ID, on the eBay site with the given location domain.
+func ItemURL(ID string, domain string) string {
+	site, ok := LookupSite(domain)
+	if !ok {
+		site, _ = LookupSite("com")
+	}
+
+	return fmt.Sprintf("https://%s/itm/%s", site.Host, ID)
+}
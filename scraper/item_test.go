@@ -0,0 +1,77 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseItemState(t *testing.T) {
+	tests := map[string]ItemState{
+		"evo.html": {
+			Title:     "Puma Powercamp 2.0 Training Ball Mens Soccer Cleats - Size 5",
+			Price:     "US $19.99",
+			Available: -1,
+		},
+		"ended.html": {
+			Title:     "Leica Summicron 50mm",
+			Price:     "US $420.00",
+			Bids:      12,
+			Available: -1,
+			Ended:     true,
+		},
+		"available.html": {
+			Title:     "Duct tape, silver, 50m",
+			Price:     "US $9.99",
+			Available: 10,
+		},
+	}
+
+	for file, exp := range tests {
+		got := parseItemState(loadFixture(t, "item", file))
+		if !reflect.DeepEqual(exp, got) {
+			t.Errorf("expected %+v but got %+v for %s", exp, got, file)
+		}
+	}
+}
+
+func TestParseAvailable(t *testing.T) {
+	tests := map[string]int{
+		"3 available":            3,
+		"More than 10 available": 10,
+		"Last one":               1,
+		"1.234 verfügbar":        1234,
+		"":                       -1,
+	}
+
+	for str, exp := range tests {
+		if got := parseAvailable(str); got != exp {
+			t.Errorf("expected %d but got %d for %q", exp, got, str)
+		}
+	}
+}
+
+func TestParseItemRef(t *testing.T) {
+	tests := map[string][2]string{
+		"402943017690": {"402943017690", "https://www.ebay.com/itm/402943017690"},
+		"https://www.ebay.co.uk/itm/402943017690?hash=item5dd14682da": {"402943017690", "https://www.ebay.co.uk/itm/402943017690"},
+		"https://www.ebay.de/itm/Duct-tape/402943017690":              {"402943017690", "https://www.ebay.de/itm/402943017690"},
+	}
+
+	for ref, exp := range tests {
+		ID, URL, err := ParseItemRef(ref)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", ref, err)
+			continue
+		}
+
+		if ID != exp[0] || URL != exp[1] {
+			t.Errorf("expected %v but got %s %s for %s", exp, ID, URL, ref)
+		}
+	}
+
+	for _, ref := range []string{"tape", "https://www.amazon.com/dp/402943017690", "https://www.ebay.com/sch/i.html"} {
+		if _, _, err := ParseItemRef(ref); err == nil {
+			t.Errorf("expected an error for %s", ref)
+		}
+	}
+}
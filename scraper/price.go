@@ -0,0 +1,131 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Price is the numeric price of a listing.
+type Price struct {
+	Amount float64
+	// Currency is the ISO 4217 code of the currency, e.g. USD.
+	Currency string
+}
+
+// currencySymbols maps the currency symbols printed by the eBay sites to their ISO 4217 code, longest first so that
+// e.g. AU $ is not read as $. The empty code stands for the currency of the site.
+var currencySymbols = []struct {
+	symbol   string
+	currency string
+}{
+	{"AU $", "AUD"},
+	{"C $", "CAD"},
+	{"US $", "USD"},
+	{"HK$", "HKD"},
+	{"S$", "SGD"},
+	{"Rs.", "INR"},
+	{"zł", "PLN"},
+	{"RM", "MYR"},
+	{"£", "GBP"},
+	{"€", "EUR"},
+	{"₹", "INR"},
+	{"₱", "PHP"},
+	{"$", ""},
+}
+
+var (
+	currencyCodeRegexp = regexp.MustCompile(`\b[A-Z]{3}\b`)
+	amountRegexp       = regexp.MustCompile(`\d[\d\s\x{00a0}\x{202f}.,']*`)
+)
+
+// ParsedPrice returns the numeric price of the listing. For a price range, the lowest price is returned. When the
+// price does not tell its currency, the currency of the eBay site of the listing is used.
+func (l Listing) ParsedPrice() (Price, error) {
+	currency := ""
+	if u, err := url.Parse(l.URL); err == nil {
+		if site, ok := LookupSiteByHost(u.Host); ok {
+			currency = site.Currency
+		}
+	}
+
+	return parsePrice(l.Price, currency)
+}
+
+// parsePrice parses the given price as printed on eBay, e.g. $19.99, 1.234,56 EUR or £10.00 to £20.00, using the
+// given currency when the price does not tell it.
+func parsePrice(str string, siteCurrency string) (Price, error) {
+	currency := currencyCodeRegexp.FindString(str)
+	if currency == "" {
+		for _, s := range currencySymbols {
+			if strings.Contains(str, s.symbol) {
+				currency = s.currency
+				break
+			}
+		}
+	}
+
+	if currency == "" {
+		currency = siteCurrency
+	}
+
+	amount := amountRegexp.FindString(str)
+	if amount == "" {
+		return Price{}, fmt.Errorf("no amount in price %s", str)
+	}
+
+	value, err := parseAmount(amount)
+	if err != nil {
+		return Price{}, fmt.Errorf("could not parse price %s: %v", str, err)
+	}
+
+	return Price{Amount: value, Currency: currency}, nil
+}
+
+// parseAmount parses the given amount, whatever its thousands and decimal separators, e.g. 1,234.56, 1.234,56,
+// 1 234,56 or 1'234.56.
+func parseAmount(amount string) (float64, error) {
+	amount = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\u202f' || r == '\'' {
+			return -1
+		}
+		return r
+	}, amount)
+	amount = strings.TrimRight(amount, ".,")
+
+	lastComma := strings.LastIndex(amount, ",")
+	lastDot := strings.LastIndex(amount, ".")
+	decimal := -1
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		// The last separator is the decimal one.
+		decimal = lastComma
+		if lastDot > lastComma {
+			decimal = lastDot
+		}
+	case lastComma >= 0 || lastDot >= 0:
+		sep := lastComma
+		if lastDot >= 0 {
+			sep = lastDot
+		}
+		// A single separator followed by 3 digits separates the thousands, as prices have no 3 decimals.
+		if strings.Count(amount, amount[sep:sep+1]) == 1 && len(amount)-sep-1 != 3 {
+			decimal = sep
+		}
+	}
+
+	var b strings.Builder
+	for i, r := range amount {
+		switch {
+		case i == decimal:
+			b.WriteRune('.')
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		}
+	}
+
+	return strconv.ParseFloat(b.String(), 64)
+}
@@ -0,0 +1,54 @@
+package scraper
+
+import "testing"
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		price        string
+		siteCurrency string
+		exp          Price
+	}{
+		{price: "$19.99", siteCurrency: "USD", exp: Price{Amount: 19.99, Currency: "USD"}},
+		{price: "$1,234.56", siteCurrency: "USD", exp: Price{Amount: 1234.56, Currency: "USD"}},
+		{price: "$10.00 to $20.00", siteCurrency: "USD", exp: Price{Amount: 10, Currency: "USD"}},
+		{price: "C $25.00", siteCurrency: "USD", exp: Price{Amount: 25, Currency: "CAD"}},
+		{price: "AU $1,000.00", siteCurrency: "AUD", exp: Price{Amount: 1000, Currency: "AUD"}},
+		{price: "£1,250", siteCurrency: "GBP", exp: Price{Amount: 1250, Currency: "GBP"}},
+		{price: "1 234,56 EUR", siteCurrency: "EUR", exp: Price{Amount: 1234.56, Currency: "EUR"}},
+		{price: "1 234,56 €", siteCurrency: "EUR", exp: Price{Amount: 1234.56, Currency: "EUR"}},
+		{price: "EUR 1.234,56", siteCurrency: "EUR", exp: Price{Amount: 1234.56, Currency: "EUR"}},
+		{price: "EUR 12,50", siteCurrency: "EUR", exp: Price{Amount: 12.5, Currency: "EUR"}},
+		{price: "CHF 1'234.50", siteCurrency: "CHF", exp: Price{Amount: 1234.5, Currency: "CHF"}},
+		{price: "19.99 USD", siteCurrency: "", exp: Price{Amount: 19.99, Currency: "USD"}},
+		{price: "1.500", siteCurrency: "EUR", exp: Price{Amount: 1500, Currency: "EUR"}},
+		{price: "12,3 zł", siteCurrency: "PLN", exp: Price{Amount: 12.3, Currency: "PLN"}},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePrice(tt.price, tt.siteCurrency)
+		if err != nil {
+			t.Errorf("could not parse price %s: %v", tt.price, err)
+			continue
+		}
+
+		if got != tt.exp {
+			t.Errorf("expected %+v but got %+v for %s", tt.exp, got, tt.price)
+		}
+	}
+
+	if _, err := parsePrice("See price", "USD"); err == nil {
+		t.Errorf("expected an error for a price without amount")
+	}
+}
+
+func TestListingParsedPrice(t *testing.T) {
+	listing := Listing{URL: "https://www.ebay.co.uk/itm/1", Price: "12.00"}
+	got, err := listing.ParsedPrice()
+	if err != nil {
+		t.Fatalf("could not parse price: %v", err)
+	}
+
+	if got.Currency != "GBP" || got.Amount != 12 {
+		t.Errorf("expected 12 GBP but got %+v", got)
+	}
+}
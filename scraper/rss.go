@@ -0,0 +1,173 @@
+package scraper
+
+import (
+	"bytes"
+	"ebay-watchdog/web"
+	"encoding/xml"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RSSSource fetches the listings from the RSS feed of the eBay search results, which is lighter and more stable than
+// the search results pages.
+type RSSSource struct {
+	Client *web.Client
+}
+
+func NewRSSSource(client *web.Client) *RSSSource {
+	return &RSSSource{Client: client}
+}
+
+type rssFeed struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	GUID        string `xml:"guid"`
+}
+
+// Fetch implements Source.
+func (r *RSSSource) Fetch(searchURL SearchURL, URL string, domain string) (Page, error) {
+	feedURL, err := rssURL(URL)
+	if err != nil {
+		return Page{}, err
+	}
+
+	body, err := r.Client.GetBody(feedURL)
+	if err != nil {
+		return Page{}, fmt.Errorf("could not make request to RSS feed %s: %w", feedURL, err)
+	}
+
+	loc, err := siteLocation(domain)
+	if err != nil {
+		return Page{}, err
+	}
+
+	listings, err := parseFeed(body, loc)
+	if err != nil {
+		return Page{}, &web.ParseError{URL: feedURL, Err: err}
+	}
+
+	return Page{Listings: listings}, nil
+}
+
+// rssURL returns the RSS feed URL of the given search URL.
+func rssURL(URL string) (string, error) {
+	u, err := url.Parse(URL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse search URL %s: %v", URL, err)
+	}
+
+	query := u.Query()
+	query.Set("_rss", "1")
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// parseFeed returns the listings of the given RSS feed, whose dates are printed in the given site timezone. The items
+// whose publication date cannot be parsed are skipped.
+func parseFeed(body []byte, loc *time.Location) ([]Listing, error) {
+	var feed rssFeed
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	// eBay feeds are not always declared as UTF-8, but they are.
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	err := decoder.Decode(&feed)
+	if err != nil {
+		return nil, err
+	}
+
+	listings := make([]Listing, 0, len(feed.Channel.Items))
+	for _, item := range feed.Channel.Items {
+		date, err := parsePubDate(item.PubDate, loc)
+		if err != nil {
+			log.Printf("Skipping RSS item %s: %v\n", strings.TrimSpace(item.Link), err)
+			continue
+		}
+
+		URL := strings.TrimSpace(item.Link)
+		split := strings.Split(URL, "/")
+
+		listing := Listing{
+			URL:   URL,
+			Title: strings.TrimSpace(item.Title),
+			Date:  date,
+			ID:    split[len(split)-1],
+		}
+
+		// The price and the image are only part of the HTML description.
+		desc, err := goquery.NewDocumentFromReader(strings.NewReader(item.Description))
+		if err == nil {
+			listing.Price = strings.Join(strings.Fields(desc.Find("strong").First().Text()), " ")
+			listing.Image, _ = desc.Find("img").Attr("src")
+		}
+
+		listings = append(listings, listing)
+	}
+
+	return listings, nil
+}
+
+// timezoneAbbreviations are the offsets, in hours, of the timezone abbreviations which can be found in the eBay feeds.
+// Go only knows the abbreviations of the local timezone, and parses the other ones as UTC. The abbreviations which are
+// not listed, or which are ambiguous such as IST, are read in the timezone of the site.
+var timezoneAbbreviations = map[string]int{
+	"GMT":  0,
+	"UTC":  0,
+	"BST":  1,
+	"WET":  0,
+	"WEST": 1,
+	"CET":  1,
+	"CEST": 2,
+	"EST":  -5,
+	"EDT":  -4,
+	"CST":  -6,
+	"CDT":  -5,
+	"MST":  -7,
+	"MDT":  -6,
+	"PST":  -8,
+	"PDT":  -7,
+	"AEST": 10,
+	"AEDT": 11,
+	"SGT":  8,
+	"MYT":  8,
+	"PHT":  8,
+}
+
+// parsePubDate parses the given RSS pubDate, e.g. "Fri, 25 Jun 2021 12:07:00 PDT" or "Fri, 25 Jun 2021 19:07:00 +0000".
+// The unknown timezone abbreviations are read in the given site timezone.
+func parsePubDate(str string, loc *time.Location) (time.Time, error) {
+	str = strings.TrimSpace(str)
+
+	t, err := time.Parse(time.RFC1123Z, str)
+	if err == nil {
+		return t, nil
+	}
+
+	t, err = time.Parse(time.RFC1123, str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse publication date %s: %v", str, err)
+	}
+
+	zone := loc
+	name, _ := t.Zone()
+	if offset, ok := timezoneAbbreviations[name]; ok {
+		zone = time.FixedZone(name, offset*3600)
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), zone), nil
+}
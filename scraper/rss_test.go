@@ -0,0 +1,154 @@
+package scraper
+
+import (
+	"ebay-watchdog/web"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFeed(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "rss", "search.xml"))
+	if err != nil {
+		t.Fatalf("could not read fixture: %v", err)
+	}
+
+	got, err := parseFeed(body, mustSiteLocation(t, "com"))
+	if err != nil {
+		t.Fatalf("could not parse feed: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 listings but got %d", len(got))
+	}
+
+	exp := Listing{
+		URL:   "https://www.ebay.com/itm/402943017690",
+		Title: "Puma Powercamp 2.0 Training  Ball Mens Soccer Cleats     - Size 5",
+		Price: "$19.99",
+		ID:    "402943017690",
+		Image: "https://thumbs.ebaystatic.com/images/g/RdAAAOSwudVg0-jc/s-l225.jpg",
+	}
+
+	if !got[0].Date.Equal(time.Date(2021, 6, 25, 19, 7, 10, 0, time.UTC)) {
+		t.Errorf("expected the date to be read as PDT but got %v", got[0].Date)
+	}
+
+	got[0].Date = time.Time{}
+	if !reflect.DeepEqual(exp, got[0]) {
+		t.Errorf("expected %+v but got %+v", exp, got[0])
+	}
+
+	if !got[1].Date.Equal(time.Date(2021, 6, 25, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date %v", got[1].Date)
+	}
+
+	t.Run("Items with an invalid date are skipped", func(t *testing.T) {
+		feed := `<rss><channel>` +
+			`<item><link>https://www.ebay.com/itm/2</link><pubDate>Fri, 25 Jun 2021 12:07:10 PDT</pubDate></item>` +
+			`<item><link>https://www.ebay.com/itm/1</link><pubDate>yesterday</pubDate></item>` +
+			`</channel></rss>`
+
+		got, err := parseFeed([]byte(feed), mustSiteLocation(t, "com"))
+		if err != nil {
+			t.Fatalf("could not parse feed: %v", err)
+		}
+
+		if len(got) != 1 || got[0].ID != "2" {
+			t.Errorf("expected only the item with a valid date, got %+v", got)
+		}
+	})
+}
+
+func TestParsePubDate(t *testing.T) {
+	// Whatever the local timezone, the feed dates must give the same instant.
+	defer pinLocalTimezone()()
+
+	tests := map[string]time.Time{
+		"Fri, 25 Jun 2021 12:07:10 PDT":   time.Date(2021, 6, 25, 19, 7, 10, 0, time.UTC),
+		"Fri, 25 Jun 2021 20:07:10 BST":   time.Date(2021, 6, 25, 19, 7, 10, 0, time.UTC),
+		"Fri, 25 Jun 2021 19:07:10 GMT":   time.Date(2021, 6, 25, 19, 7, 10, 0, time.UTC),
+		"Fri, 25 Jun 2021 21:07:10 +0200": time.Date(2021, 6, 25, 19, 7, 10, 0, time.UTC),
+	}
+
+	for str, exp := range tests {
+		got, err := parsePubDate(str, mustSiteLocation(t, "com"))
+		if err != nil {
+			t.Errorf("could not parse %s: %v", str, err)
+			continue
+		}
+
+		if !got.Equal(exp) {
+			t.Errorf("expected %v but got %v for %s", exp, got, str)
+		}
+	}
+
+	t.Run("Unknown abbreviations are read in the site timezone", func(t *testing.T) {
+		siteTests := map[string]struct {
+			domain string
+			exp    time.Time
+		}{
+			"Fri, 25 Jun 2021 19:07:10 HKT": {"com.hk", time.Date(2021, 6, 25, 11, 7, 10, 0, time.UTC)},
+			"Fri, 25 Jun 2021 19:07:10 IST": {"in", time.Date(2021, 6, 25, 13, 37, 10, 0, time.UTC)},
+		}
+
+		for str, test := range siteTests {
+			got, err := parsePubDate(str, mustSiteLocation(t, test.domain))
+			if err != nil {
+				t.Errorf("could not parse %s: %v", str, err)
+				continue
+			}
+
+			if !got.Equal(test.exp) {
+				t.Errorf("expected %v but got %v for %s", test.exp, got, str)
+			}
+		}
+	})
+
+	_, err := parsePubDate("yesterday", time.UTC)
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestRSSURL(t *testing.T) {
+	got, err := rssURL("https://www.ebay.com/sch/i.html?_nkw=duct+tape&_sop=10")
+	if err != nil {
+		t.Fatalf("could not build the RSS URL: %v", err)
+	}
+
+	exp := "https://www.ebay.com/sch/i.html?_nkw=duct+tape&_rss=1&_sop=10"
+	if got != exp {
+		t.Errorf("expected %s but got %s", exp, got)
+	}
+}
+
+func TestRSSFetchErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("_nkw") == "gone" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, "<rss><channel><item>")
+	}))
+	defer server.Close()
+
+	source := NewRSSSource(web.DefaultClient)
+
+	URL := server.URL + "/sch/i.html?_nkw=tape"
+	if _, err := source.Fetch(SearchURL{URL: URL}, URL, "com"); !errors.As(err, new(*web.ParseError)) {
+		t.Errorf("expected a ParseError for a truncated feed, got %v", err)
+	}
+
+	URL = server.URL + "/sch/i.html?_nkw=gone"
+	var statusErr *web.StatusError
+	if _, err := source.Fetch(SearchURL{URL: URL}, URL, "com"); !errors.As(err, &statusErr) || statusErr.StatusCode != 404 {
+		t.Errorf("expected a 404 StatusError, got %v", err)
+	}
+}
@@ -3,47 +3,133 @@ package scraper
 import (
 	"ebay-watchdog/cache"
 	"ebay-watchdog/web"
+	"errors"
 	"fmt"
-	"github.com/PuerkitoBio/goquery"
 	"log"
+	"net/url"
 	"strings"
 	"time"
 )
 
 type Scraper struct {
 	URLs []SearchURL
+	// Sources are the available listing sources, by name. See SourceHTML and SourceAPI.
+	Sources map[string]Source
+	// Pause is the period between two requests, to prevent getting throttled.
+	Pause time.Duration
+	// Now returns the scraping time, used to tell which auctions are ending soon.
+	Now func() time.Time
 }
 
 type SearchURL struct {
 	URL     string
 	Domains []string
+	// Source is the name of the listing source to use for this search. Defaults to SourceHTML.
+	Source string
+	// FlagFewerWords keeps scraping past the "Results matching fewer words" divider, flagging the listings found
+	// below it, instead of stopping there.
+	FlagFewerWords bool
+	// NotifySponsored keeps the new sponsored listings in the scraped listings. They are skipped otherwise.
+	NotifySponsored bool
+	// MaxPages is the maximum number of pages read when the last scraped listing is not found on the first page.
+	// Defaults to DefaultMaxPages.
+	MaxPages int
+	// Mode is ModeNewest (default) or ModeEnding.
+	Mode string
+	// Ending configures the ModeEnding searches.
+	Ending Ending
 }
 
-func NewScraper(URLs []SearchURL) *Scraper {
-	return &Scraper{URLs: URLs}
+// DefaultMaxPages is the maximum number of pages read for a search which does not set it.
+const DefaultMaxPages = 5
+
+func NewScraper(URLs []SearchURL, sources map[string]Source) *Scraper {
+	return &Scraper{
+		URLs:    URLs,
+		Sources: sources,
+		Pause:   2 * time.Second,
+		Now:     time.Now,
+	}
 }
 
 type Listing struct {
-	URL      string    `json:"url"`
-	Title    string    `json:"title"`
-	Subtitle string    `json:"subtitle"`
-	Price    string    `json:"price"`
-	Date     time.Time `json:"date"`
-	ID       string    `json:"id"`
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	Subtitle  string    `json:"subtitle"`
+	Price     string    `json:"price"`
+	Date      time.Time `json:"date"`
+	ID        string    `json:"id"`
+	Image     string    `json:"image"`
+	Condition string    `json:"condition"`
+	Shipping  string    `json:"shipping"`
+	Location  string    `json:"location"`
+	// EndsAt is the end of the auctions found by the ModeEnding searches, and Bids their number of bids.
+	EndsAt time.Time `json:"ends_at"`
+	Bids   int       `json:"bids"`
+	// FewerWords is true when the listing comes from the "Results matching fewer words" section of the results.
+	FewerWords bool `json:"fewer_words"`
+	// Sponsored is true for the promoted listings, which are not sorted by publication date.
+	Sponsored bool `json:"sponsored"`
+	// SearchURL is the URL of the search which found the listing, as configured.
+	SearchURL string `json:"search_url"`
+	// ConvertedPrice is the price converted into the base currency, when it is in another currency.
+	ConvertedPrice string `json:"converted_price"`
+	// Tags are added by the rules of the search, and Silent is set when the listing must be notified without sound.
+	Tags   []string `json:"tags"`
+	Silent bool     `json:"silent"`
+	// RelistOf is the URL of the previously notified listing of which this listing is a relist, if any.
+	RelistOf string `json:"relist_of"`
+	// Deal compares the price to the recently sold listings of the search. It is nil when they are unknown.
+	Deal *Deal `json:"deal,omitempty"`
+
+	// The following fields are only set when the listings are enriched with their item page. Enriched is true once
+	// the item page was read.
+	Enriched              bool              `json:"enriched"`
+	Seller                string            `json:"seller"`
+	SellerFeedbackScore   int               `json:"seller_feedback_score"`
+	SellerFeedbackPercent string            `json:"seller_feedback_percent"`
+	Specifics             map[string]string `json:"specifics"`
+	Description           string            `json:"description"`
+	ReturnsPolicy         string            `json:"returns_policy"`
+}
+
+// Deal compares the price of a listing to the prices of the recently sold listings of its search.
+type Deal struct {
+	// Percentile is the percentage of sold listings which were cheaper, from 0 to 100.
+	Percentile float64 `json:"percentile"`
+	// Median is the median price of the sold listings, and Difference the price of the listing minus the median.
+	Median     float64 `json:"median"`
+	Difference float64 `json:"difference"`
+	Currency   string  `json:"currency"`
+	// Samples is the number of sold listings.
+	Samples int `json:"samples"`
+}
+
+// ScrapedSearch is what was found on a search URL during a scraping loop. It is used when updating the cache.
+type ScrapedSearch struct {
+	// Last is the most recent listing, sponsored listings excluded. It is nil if there was none.
+	Last *Listing
+	// Seen are the item IDs of the new listings, newest first.
+	Seen []string
+	// Alerted are the auctions notified by a ModeEnding search.
+	Alerted []cache.AlertedAuction
+	// Err is the error which stopped the fetching of the search pages, if any. It is a *web.BlockedError for the
+	// searches which were skipped because their site blocked a previous request.
+	Err error
 }
 
 // Scrape starts the scraping for the given []scraper.SearchURL.
-// It returns a list of Listing, to be sent to Telegram. It also returns a map[string]Listing which will be used when
-// updating the cache.
+// It returns a list of Listing, to be sent to Telegram. It also returns a map[string]ScrapedSearch which will be used
+// when updating the cache.
 func (s *Scraper) Scrape(
 	cache map[string]cache.CachedListing,
 ) (
 	[]Listing,
-	map[string]Listing,
+	map[string]ScrapedSearch,
 	error,
 ) {
 	log.Println("Scraping new listings")
-	listings, lastItems, err := scrapeListings(s.URLs, cache)
+	listings, lastItems, err := s.scrapeListings(cache)
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not start scraping: %v", err)
 	}
@@ -52,22 +138,25 @@ func (s *Scraper) Scrape(
 	return listings, lastItems, nil
 }
 
-func scrapeListings(
-	searchURLs []SearchURL,
+func (s *Scraper) scrapeListings(
 	scraped map[string]cache.CachedListing,
 ) (
 	[]Listing,
-	map[string]Listing,
+	map[string]ScrapedSearch,
 	error,
 ) {
 	var pulledListings []Listing
-	lastItems := make(map[string]Listing)
+	lastItems := make(map[string]ScrapedSearch)
 
 	// Keep in memory the id of the parsed listings, so we do not send the same listing twice when checking
 	// multiple domains.
 	currentSearchURLs := make(map[string]int)
 
-	for _, searchURL := range searchURLs {
+	// The sites which blocked a request are not requested again until the next scraping loop. The Browse API is
+	// blocked on its own, see blockKey.
+	blocked := make(map[string]*web.BlockedError)
+
+	for _, searchURL := range s.URLs {
 		if searchURL.Domains == nil || len(searchURL.Domains) == 0 {
 			domain, err := parseLocDomain(searchURL.URL)
 			if err != nil {
@@ -77,154 +166,271 @@ func scrapeListings(
 			searchURL.Domains = []string{domain}
 		}
 
+		source, err := s.sourceFor(searchURL)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if searchURL.Mode == ModeEnding {
+			for _, domain := range searchURL.Domains {
+				URL, err := setDomain(searchURL.URL, domain)
+				if err != nil {
+					return nil, nil, fmt.Errorf("could not set domain %s for url %s: %s", domain, searchURL.URL, err)
+				}
+
+				if blockedErr, ok := blocked[blockKey(searchURL, domain)]; ok {
+					log.Printf("Skipping url %s, %s is blocked\n", URL, blockKey(searchURL, domain))
+					lastItems[URL] = ScrapedSearch{Err: blockedErr}
+					continue
+				}
+
+				listings, alerted, err := s.scrapeEnding(source, searchURL, URL, domain, scraped[URL])
+				if blockedErr := blockedError(err); blockedErr != nil {
+					blocked[blockKey(searchURL, domain)] = blockedErr
+				}
+				for _, listing := range listings {
+					if _, isKnownID := currentSearchURLs[listing.ID]; !isKnownID {
+						currentSearchURLs[listing.ID] = 1
+						pulledListings = append(pulledListings, listing)
+					}
+				}
+				lastItems[URL] = ScrapedSearch{Alerted: alerted, Err: err}
+
+				// We space each queries just in case, to prevent getting throttled
+				time.Sleep(s.Pause)
+			}
+			continue
+		}
+
 		for _, domain := range searchURL.Domains {
 			URL, err := setDomain(searchURL.URL, domain)
 			if err != nil {
 				return nil, nil, fmt.Errorf("could not set domain %s for url %s: %s", domain, searchURL.URL, err)
 			}
 
-			log.Printf("Searching with url %s (domain %s)\n", URL, domain)
-			doc, err := web.Get(URL)
-			if err != nil {
-				log.Printf("could not make request to search URL page %s: %s\n", URL, err)
+			if blockedErr, ok := blocked[blockKey(searchURL, domain)]; ok {
+				log.Printf("Skipping url %s, %s is blocked\n", URL, blockKey(searchURL, domain))
+				lastItems[URL] = ScrapedSearch{Err: blockedErr}
 				continue
 			}
 
-			if doc == nil {
-				log.Printf("received an empty result for URL %s, skipping...\n", URL)
-				continue
-			}
+			log.Printf("Searching with url %s (domain %s)\n", URL, domain)
 
 			isFirst := true
+			pageURL := URL
+			for pageNumber := 1; ; pageNumber++ {
+				page, err := source.Fetch(searchURL, pageURL, domain)
+				if err != nil {
+					log.Printf("could not fetch the listings of search URL %s: %s\n", pageURL, err)
+					scrapedSearch := lastItems[URL]
+					scrapedSearch.Err = err
+					lastItems[URL] = scrapedSearch
+					if blockedErr := blockedError(err); blockedErr != nil {
+						blocked[blockKey(searchURL, domain)] = blockedErr
+					}
+					break
+				}
 
-			riverResults := doc.Find("div#srp-river-results")
-			if riverResults == nil {
-				log.Printf("could not find srp-river-results div for URL %s, skipping...\n", searchURL)
-				continue
-			}
-
-			itemInfoList := riverResults.Find("div.s-item__info")
-			if itemInfoList == nil {
-				log.Printf("received zero items for URL %s, skipping...\n", searchURL)
-				continue
-			}
+				stopped := false
+				for i, listing := range page.Listings {
+					isNew, b := parseItem(listing, scraped, URL)
+					if isNew {
+						_, isKnownID := currentSearchURLs[listing.ID]
+						if !isKnownID && keepListing(listing, searchURL, scraped, URL) {
+							currentSearchURLs[listing.ID] = 1
+							listing.SearchURL = searchURL.URL
+							pulledListings = append(pulledListings, listing)
+						}
+
+						scrapedSearch := lastItems[URL]
+						scrapedSearch.Seen = append(scrapedSearch.Seen, listing.ItemID())
+
+						// Sponsored listings are not sorted by date, so they cannot be used as the last scraped item.
+						if isFirst && !listing.Sponsored {
+							last := listing
+							scrapedSearch.Last = &last
+
+							isFirst = false
+						}
+
+						lastItems[URL] = scrapedSearch
+					}
 
-			itemInfoList.EachWithBreak(func(i int, sel *goquery.Selection) bool {
-				listing, b := parseItem(sel, scraped, URL)
-				if listing != nil {
-					_, isKnownID := currentSearchURLs[listing.ID]
-					if !isKnownID {
-						currentSearchURLs[listing.ID] = 1
-						pulledListings = append(pulledListings, *listing)
+					if !b {
+						if _, isKnownURL := scraped[URL]; !isKnownURL {
+							seedSeen(lastItems, URL, page.Listings[i+1:])
+						}
+						stopped = true
+						break
 					}
+				}
 
-					if isFirst {
-						lastItems[URL] = *listing
+				if stopped || page.Next == "" {
+					break
+				}
 
-						isFirst = false
-					}
+				if pageNumber >= searchURL.maxPages() {
+					log.Printf("Reached the page limit (%d) before the last scraped listing for URL %s\n", pageNumber, URL)
+					break
 				}
 
-				return b
-			})
+				// We space each queries just in case, to prevent getting throttled
+				time.Sleep(s.Pause)
+
+				log.Printf("Last scraped listing not reached, following the next page of URL %s\n", URL)
+				pageURL = page.Next
+			}
 
 			// We space each queries just in case, to prevent getting throttled
-			time.Sleep(2 * time.Second)
+			time.Sleep(s.Pause)
 		}
 	}
 
 	return pulledListings, lastItems, nil
 }
 
-func parseItem(
-	sel *goquery.Selection,
-	scraped map[string]cache.CachedListing,
-	searchUrl string,
-) (*Listing, bool) {
-	_, isKnownURL := scraped[searchUrl]
+// blockKey returns the key under which a block of the given search is remembered during a scraping loop: the Browse
+// API rate limit does not block the pages of the eBay sites, and the other way round.
+func blockKey(searchURL SearchURL, domain string) string {
+	if searchURL.Source == SourceAPI {
+		return "the " + SourceAPI + " source"
+	}
+
+	return "domain " + domain
+}
 
-	itemSel := sel.Children()
-	if len(itemSel.Nodes) < 3 {
-		return nil, true
+// seedSeen adds the given listings to the seen listings of the given search URL, sponsored listings excluded. On the
+// first scraping of a search URL, the rest of the first page is not notified but remembered, so that the next
+// scraping still meets a seen listing when the first one ends or is deleted.
+func seedSeen(lastItems map[string]ScrapedSearch, URL string, listings []Listing) {
+	scrapedSearch := lastItems[URL]
+	for _, listing := range listings {
+		if !listing.Sponsored {
+			scrapedSearch.Seen = append(scrapedSearch.Seen, listing.ItemID())
+		}
 	}
+	lastItems[URL] = scrapedSearch
+}
 
-	rawURL, exists := itemSel.Attr("href")
-	if !exists {
-		return nil, true
+// blockedError returns the *web.BlockedError of the given fetch error, or nil if it has none.
+func blockedError(err error) *web.BlockedError {
+	var blockedErr *web.BlockedError
+	if errors.As(err, &blockedErr) {
+		return blockedErr
 	}
 
-	// Listing URLs with amdata generate different URLs for the same listings
-	// Removing the amdata allows us to determine if a listing has already been scraped or not.
-	URL := strings.Split(rawURL, "&amdata")[0]
+	return nil
+}
 
-	if isKnownURL && scraped[searchUrl].URL == URL {
-		log.Println("Stop - Reached a listing that has already been scraped!")
-		return nil, false
+// ItemID returns the eBay item ID of the listing, e.g. 402943017690 for
+// https://www.ebay.com/itm/402943017690?hash=item5dd14682da:g:RdAAAOSwudVg0-jc. Unlike the ID, it does not depend on
+// the tracking parameters of the listing URL.
+func (l Listing) ItemID() string {
+	u, err := url.Parse(l.URL)
+	if err == nil {
+		split := strings.Split(strings.TrimSuffix(u.Path, "/"), "/")
+		if len(split) > 0 && isDigits(split[len(split)-1]) {
+			return split[len(split)-1]
+		}
 	}
 
-	title := sel.Find(".s-item__title").Text()
-	subtitle := sel.Find(".s-item__subtitle").Text()
-	detailsSel := sel.Find(".s-item__details").Children()
-	price := detailsSel.Find(".s-item__price").Text()
-	date := detailsSel.Find(".s-item__listingDate").Text()
+	return strings.Split(l.ID, "?")[0]
+}
 
-	t, err := parseDate(date, URL)
-	if err != nil {
-		log.Println("error while parsing date", date, err)
-		return nil, false
+// isDigits returns whether the given string is a non-empty sequence of digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
 	}
 
-	lastScrapedProductDate := scraped[searchUrl].Date.Add(time.Hour * time.Duration(-1))
-	// In case the last scraped product has been deleted, we can still compare the dates
-	if isKnownURL && t.Before(lastScrapedProductDate) {
-		log.Println("Stop - Reached a listing that has an older publication date than the last scraped listing!")
-		return nil, false
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
 	}
 
-	split := strings.Split(URL, "/")
+	return true
+}
 
-	listing := Listing{
-		URL:      URL,
-		Title:    title,
-		Subtitle: subtitle,
-		Price:    price,
-		Date:     t,
-		ID:       split[len(split)-1],
+// maxPages returns the maximum number of pages to read for the search.
+func (s SearchURL) maxPages() int {
+	if s.MaxPages <= 0 {
+		return DefaultMaxPages
 	}
 
-	log.Printf("Successfully scraped 1 listing details (ID: %s)\n", listing.ID)
+	return s.MaxPages
+}
 
-	if !isKnownURL {
-		// This was the first time scraping this searchUrl. As we only want to check for new listings,
-		// we won't scrape all the next listings and we will just wait for new ones. This is why we
-		// will break out of the loop.
-		return &listing, false
+// sourceFor returns the listing source to use for the given search.
+func (s *Scraper) sourceFor(searchURL SearchURL) (Source, error) {
+	name := searchURL.Source
+	if name == "" {
+		name = SourceHTML
+	}
+
+	source, ok := s.Sources[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown listing source %s for url %s", name, searchURL.URL)
 	}
 
-	return &listing, true
+	return source, nil
 }
 
-// setDomain replaces the top level domain of the given URL by the given domain, and returns the new URL.
-func setDomain(URL string, domain string) (string, error) {
-	split := strings.Split(URL, "/")
+// parseItem decides what to do with the given listing, coming from the newest-first results of the given search
+// URL. It returns whether the listing is new, and whether the next listings should be checked as well.
+// A listing is new when it is not among the recently seen listings of the search URL. The results are read until a
+// seen listing, or as a fallback when the seen listings have ended or were deleted, until a listing published more
+// than an hour before the last scraped listing.
+func parseItem(
+	listing Listing,
+	scraped map[string]cache.CachedListing,
+	searchUrl string,
+) (bool, bool) {
+	last, isKnownURL := scraped[searchUrl]
+	isSeen := isKnownURL && last.HasSeen(listing.ItemID())
+
+	// Sponsored listings are not sorted by date: they must not trigger the stop conditions.
+	if listing.Sponsored {
+		return !isSeen, true
+	}
+
+	// The URL is only checked for the caches which do not have the seen listings yet.
+	if isSeen || (isKnownURL && last.URL == listing.URL) {
+		log.Println("Stop - Reached a listing that has already been scraped!")
+		return false, false
+	}
 
-	if len(split) < 3 {
-		return "", fmt.Errorf("could not extract domain from URL %s", URL)
+	lastScrapedProductDate := last.Date.Add(time.Hour * time.Duration(-1))
+	// When no seen listing is met, we can still compare the dates
+	if isKnownURL && listing.Date.Before(lastScrapedProductDate) {
+		log.Println("Stop - Reached a listing that has an older publication date than the last scraped listing!")
+		return false, false
 	}
 
-	split[2] = fmt.Sprintf("%s%s", "www.ebay.", domain)
+	log.Printf("Successfully scraped 1 listing details (ID: %s)\n", listing.ID)
+
+	if !isKnownURL {
+		// This was the first time scraping this searchUrl. As we only want to check for new listings,
+		// we won't scrape all the next listings and we will just wait for new ones. This is why we
+		// will break out of the loop.
+		return true, false
+	}
 
-	return strings.Join(split, "/"), nil
+	return true, true
 }
 
-// parseLocDomain returns the location domain from the given listing URL.
-// e.g. com, co.uk, fr, etc.
-func parseLocDomain(URL string) (string, error) {
-	split := strings.Split(URL, "/")
+// keepListing returns whether the given parsed listing should be part of the scraped listings.
+// Sponsored listings are only kept when the search asks for them, and when they are more recent than the last scraped
+// listing, so that old promoted listings do not resurface.
+func keepListing(listing Listing, searchURL SearchURL, scraped map[string]cache.CachedListing, URL string) bool {
+	if !listing.Sponsored {
+		return true
+	}
 
-	if len(split) < 3 {
-		return "", fmt.Errorf("could not extract location domain from URL %s", URL)
+	if !searchURL.NotifySponsored {
+		return false
 	}
 
-	return strings.Replace(split[2], "www.ebay.", "", 1), nil
+	last, isKnownURL := scraped[URL]
+	return isKnownURL && listing.Date.After(last.Date)
 }
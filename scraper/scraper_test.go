@@ -1,6 +1,10 @@
 package scraper
 
 import (
+	"ebay-watchdog/cache"
+	"ebay-watchdog/web"
+	"errors"
+	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	"reflect"
 	"strings"
@@ -18,7 +22,11 @@ func TestParseItem(t *testing.T) {
 
 	got := make([]Listing, 0)
 	doc.Find("div.s-item__info").EachWithBreak(func(i int, sel *goquery.Selection) bool {
-		listing, _ := parseItem(sel, nil, "")
+		listing, err := extractItem(sel, DefaultProfile(), testNow)
+		if err != nil {
+			t.Errorf("could not extract item: %v", err)
+		}
+
 		if listing != nil {
 			// Keep the test simple by removing dates
 			listing.Date = time.Time{}
@@ -30,12 +38,16 @@ func TestParseItem(t *testing.T) {
 
 	exp := []Listing{
 		{
-			URL:      "https://www.ebay.com/itm/402943017690?hash=item5dd14682da:g:RdAAAOSwudVg0-jc",
-			Title:    "Puma Powercamp 2.0 Training  Ball Mens Soccer Cleats     - Size 5",
-			Subtitle: "Brand New",
-			Price:    "$19.99",
-			Date:     time.Time{},
-			ID:       "402943017690?hash=item5dd14682da:g:RdAAAOSwudVg0-jc",
+			URL:       "https://www.ebay.com/itm/402943017690?hash=item5dd14682da:g:RdAAAOSwudVg0-jc",
+			Title:     "Puma Powercamp 2.0 Training  Ball Mens Soccer Cleats     - Size 5",
+			Subtitle:  "Brand New",
+			Price:     "$19.99",
+			Date:      time.Time{},
+			ID:        "402943017690?hash=item5dd14682da:g:RdAAAOSwudVg0-jc",
+			Image:     "https://i.ebayimg.com/thumbs/images/g/RdAAAOSwudVg0-jc/s-l225.webp",
+			Condition: "Brand New",
+			Shipping:  "+$33.39 shipping estimate",
+			Location:  "from United States",
 		},
 	}
 
@@ -84,3 +96,363 @@ func TestParseLocDomain(t *testing.T) {
 		t.Errorf("expected %s but got %s", exp, got)
 	}
 }
+
+// fakeSource serves predefined pages of listings, or errors, by URL.
+type fakeSource struct {
+	pages     map[string]Page
+	errs      map[string]error
+	requested []string
+}
+
+func (f *fakeSource) Fetch(searchURL SearchURL, URL string, domain string) (Page, error) {
+	f.requested = append(f.requested, URL)
+	if err, ok := f.errs[URL]; ok {
+		return Page{}, err
+	}
+	return f.pages[URL], nil
+}
+
+func (f *fakeSource) FetchSold(URL string, domain string) ([]Listing, error) {
+	page, err := f.Fetch(SearchURL{}, URL, domain)
+	return page.Listings, err
+}
+
+func fakeListings(from int, to int, date time.Time) []Listing {
+	var listings []Listing
+	for i := from; i >= to; i-- {
+		listings = append(listings, Listing{
+			ID:   fmt.Sprintf("%d", i),
+			URL:  fmt.Sprintf("https://www.ebay.com/itm/%d", i),
+			Date: date.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	return listings
+}
+
+func TestScrapeListingsPagination(t *testing.T) {
+	URL := "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10"
+	date := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+
+	source := &fakeSource{pages: map[string]Page{
+		URL:             {Listings: fakeListings(30, 21, date), Next: URL + "&_pgn=2"},
+		URL + "&_pgn=2": {Listings: fakeListings(20, 11, date), Next: URL + "&_pgn=3"},
+		URL + "&_pgn=3": {Listings: fakeListings(10, 1, date), Next: URL + "&_pgn=4"},
+	}}
+
+	scraped := map[string]cache.CachedListing{
+		URL: {URL: "https://www.ebay.com/itm/15", Date: date.Add(15 * time.Minute)},
+	}
+
+	t.Run("Follow the next pages until the last scraped listing", func(t *testing.T) {
+		source.requested = nil
+		s := NewScraper([]SearchURL{{URL: URL}}, map[string]Source{SourceHTML: source})
+		s.Pause = 0
+
+		listings, lastItems, err := s.scrapeListings(scraped)
+		if err != nil {
+			t.Fatalf("could not scrape: %v", err)
+		}
+
+		if len(listings) != 15 || listings[0].ID != "30" || listings[14].ID != "16" {
+			t.Errorf("expected the listings 30 to 16, got %d listings", len(listings))
+		}
+
+		if len(source.requested) != 2 {
+			t.Errorf("expected 2 pages to be requested but got %v", source.requested)
+		}
+
+		if lastItems[URL].Last == nil || lastItems[URL].Last.ID != "30" {
+			t.Errorf("expected the last item to be the newest listing, got %+v", lastItems[URL].Last)
+		}
+
+		if len(lastItems[URL].Seen) != 15 || lastItems[URL].Seen[0] != "30" {
+			t.Errorf("expected the 15 new listings to be seen, got %v", lastItems[URL].Seen)
+		}
+	})
+
+	t.Run("Stop at the page limit", func(t *testing.T) {
+		source.requested = nil
+		s := NewScraper([]SearchURL{{URL: URL, MaxPages: 1}}, map[string]Source{SourceHTML: source})
+		s.Pause = 0
+
+		listings, _, err := s.scrapeListings(scraped)
+		if err != nil {
+			t.Fatalf("could not scrape: %v", err)
+		}
+
+		if len(listings) != 10 || len(source.requested) != 1 {
+			t.Errorf("expected 10 listings from 1 page, got %d listings from %d pages", len(listings), len(source.requested))
+		}
+	})
+
+	t.Run("First scraping only reads the first listing", func(t *testing.T) {
+		source.requested = nil
+		s := NewScraper([]SearchURL{{URL: URL}}, map[string]Source{SourceHTML: source})
+		s.Pause = 0
+
+		listings, lastItems, err := s.scrapeListings(nil)
+		if err != nil {
+			t.Fatalf("could not scrape: %v", err)
+		}
+
+		if len(listings) != 1 || len(source.requested) != 1 {
+			t.Errorf("expected 1 listing from 1 page, got %d listings from %d pages", len(listings), len(source.requested))
+		}
+
+		if len(lastItems[URL].Seen) != 10 || lastItems[URL].Seen[0] != "30" || lastItems[URL].Seen[9] != "21" {
+			t.Errorf("expected the whole first page to be seen, got %v", lastItems[URL].Seen)
+		}
+
+		// The first listing is deleted before the next scraping: the other listings of the first page are still seen.
+		scraped := map[string]cache.CachedListing{
+			URL: cache.CachedListing{URL: lastItems[URL].Last.URL, Date: lastItems[URL].Last.Date}.
+				AddSeen(lastItems[URL].Seen, date),
+		}
+		deleted := &fakeSource{pages: map[string]Page{
+			URL: {Listings: fakeListings(29, 20, date), Next: URL + "&_pgn=2"},
+		}}
+		s = NewScraper([]SearchURL{{URL: URL}}, map[string]Source{SourceHTML: deleted})
+		s.Pause = 0
+
+		listings, _, err = s.scrapeListings(scraped)
+		if err != nil {
+			t.Fatalf("could not scrape: %v", err)
+		}
+
+		if len(listings) != 0 || len(deleted.requested) != 1 {
+			t.Errorf("expected no new listing from 1 page, got %d listings from %d pages", len(listings), len(deleted.requested))
+		}
+	})
+}
+
+func TestScrapeListingsErrors(t *testing.T) {
+	date := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	tape := "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10"
+	glue := "https://www.ebay.com/sch/i.html?_nkw=glue&_sop=10"
+	tapeDE, _ := setDomain(tape, "de")
+	glueDE, _ := setDomain(glue, "de")
+	tapeUK, _ := setDomain(tape, "co.uk")
+	glueUK, _ := setDomain(glue, "co.uk")
+
+	timeout := &web.TimeoutError{URL: tapeUK, Err: fmt.Errorf("deadline exceeded")}
+	source := &fakeSource{
+		pages: map[string]Page{
+			tape:   {Listings: fakeListings(2, 1, date)},
+			glue:   {Listings: fakeListings(4, 3, date)},
+			glueUK: {Listings: fakeListings(6, 5, date)},
+		},
+		errs: map[string]error{
+			tapeDE: fmt.Errorf("could not make request: %w", &web.BlockedError{URL: tapeDE, StatusCode: 403}),
+			tapeUK: timeout,
+		},
+	}
+
+	domains := []string{"com", "de", "co.uk"}
+	s := NewScraper([]SearchURL{{URL: tape, Domains: domains}, {URL: glue, Domains: domains}}, map[string]Source{SourceHTML: source})
+	s.Pause = 0
+
+	listings, lastItems, err := s.scrapeListings(nil)
+	if err != nil {
+		t.Fatalf("could not scrape: %v", err)
+	}
+
+	if len(listings) != 3 {
+		t.Errorf("expected 3 listings, got %d", len(listings))
+	}
+
+	for _, URL := range source.requested {
+		if URL == glueDE {
+			t.Errorf("expected the blocked domain to be skipped, but %s was requested", URL)
+		}
+	}
+
+	var blockedErr *web.BlockedError
+	if !errors.As(lastItems[tapeDE].Err, &blockedErr) || !errors.As(lastItems[glueDE].Err, &blockedErr) {
+		t.Errorf("expected the searches of the blocked domain to have a BlockedError, got %v and %v", lastItems[tapeDE].Err, lastItems[glueDE].Err)
+	}
+
+	if lastItems[tapeUK].Err != timeout {
+		t.Errorf("expected the timeout to be kept, got %v", lastItems[tapeUK].Err)
+	}
+
+	if lastItems[glueUK].Err != nil || lastItems[tape].Err != nil {
+		t.Errorf("expected no error for the other searches")
+	}
+}
+
+func TestScrapeListingsAPIBlock(t *testing.T) {
+	date := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	tape := "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10"
+	glue := "https://www.ebay.com/sch/i.html?_nkw=glue&_sop=10"
+	wood := "https://www.ebay.com/sch/i.html?_nkw=wood&_sop=10"
+
+	api := &fakeSource{errs: map[string]error{
+		tape: fmt.Errorf("could not search the Browse API: %w", &web.BlockedError{URL: "https://api.ebay.com/buy/browse/v1/item_summary/search", StatusCode: 429}),
+	}}
+	html := &fakeSource{pages: map[string]Page{glue: {Listings: fakeListings(2, 1, date)}}}
+
+	s := NewScraper(
+		[]SearchURL{{URL: tape, Source: SourceAPI}, {URL: glue}, {URL: wood, Source: SourceAPI}},
+		map[string]Source{SourceHTML: html, SourceAPI: api},
+	)
+	s.Pause = 0
+
+	_, lastItems, err := s.scrapeListings(nil)
+	if err != nil {
+		t.Fatalf("could not scrape: %v", err)
+	}
+
+	if !reflect.DeepEqual(html.requested, []string{glue}) || lastItems[glue].Err != nil {
+		t.Errorf("expected the HTML search of the same site to be requested, got %v and %v", html.requested, lastItems[glue].Err)
+	}
+
+	var blockedErr *web.BlockedError
+	if !reflect.DeepEqual(api.requested, []string{tape}) || !errors.As(lastItems[wood].Err, &blockedErr) {
+		t.Errorf("expected the other API search to be skipped, got %v and %v", api.requested, lastItems[wood].Err)
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	URL := "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10"
+	tests := map[string]string{
+		`<nav class="pagination"><a class="pagination__next" href="https://www.ebay.com/sch/i.html?_nkw=tape&amp;_sop=10&amp;_pgn=2">Next</a></nav>`: URL + "&_pgn=2",
+		`<nav class="pagination"><a class="pagination__next" href="/sch/i.html?_nkw=tape&amp;_sop=10&amp;_pgn=2">Next</a></nav>`:                     URL + "&_pgn=2",
+		`<nav class="pagination"><a class="pagination__next" aria-disabled="true">Next</a></nav>`:                                                    "",
+		`<nav class="pagination"></nav>`: "",
+	}
+
+	for html, exp := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			t.Fatalf("could not create document: %v", err)
+		}
+
+		if got := nextPageURL(doc, DefaultProfile(), URL); got != exp {
+			t.Errorf("expected %s but got %s", exp, got)
+		}
+	}
+}
+
+func TestParseItemSeenListings(t *testing.T) {
+	URL := "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10"
+	date := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+
+	// The last scraped listing (15) has been deleted, but the previous ones were seen as well.
+	scraped := map[string]cache.CachedListing{
+		URL: cache.CachedListing{URL: "https://www.ebay.com/itm/15", Date: date.Add(15 * time.Minute)}.
+			AddSeen([]string{"15", "14", "13"}, date),
+	}
+
+	listings := fakeListings(20, 1, date)
+	var got []string
+	for _, listing := range listings {
+		if listing.ID == "15" {
+			continue
+		}
+
+		// Listing URLs may come with different tracking parameters.
+		listing.URL += "?hash=item5dd14682da"
+		isNew, b := parseItem(listing, scraped, URL)
+		if isNew {
+			got = append(got, listing.ID)
+		}
+
+		if !b {
+			break
+		}
+	}
+
+	exp := []string{"20", "19", "18", "17", "16"}
+	if !reflect.DeepEqual(exp, got) {
+		t.Errorf("expected %v but got %v", exp, got)
+	}
+
+	t.Run("Seen sponsored listings are not new", func(t *testing.T) {
+		listing := Listing{ID: "14", URL: "https://www.ebay.com/itm/14", Date: date, Sponsored: true}
+		isNew, b := parseItem(listing, scraped, URL)
+		if isNew || !b {
+			t.Errorf("expected the seen sponsored listing to be skipped, got isNew=%v and b=%v", isNew, b)
+		}
+	})
+
+	t.Run("Older listings stop when the seen listings are gone", func(t *testing.T) {
+		// The only seen listing (15) ended, so the scan can only stop on the publication dates.
+		scraped := map[string]cache.CachedListing{
+			URL: cache.CachedListing{URL: "https://www.ebay.com/itm/15", Date: date.Add(15 * time.Minute)}.
+				AddSeen([]string{"15"}, date),
+		}
+
+		listings := append(fakeListings(17, 16, date), fakeListings(14, 1, date.Add(-2*time.Hour))...)
+		var got []string
+		for _, listing := range listings {
+			isNew, b := parseItem(listing, scraped, URL)
+			if isNew {
+				got = append(got, listing.ID)
+			}
+
+			if !b {
+				break
+			}
+		}
+
+		exp := []string{"17", "16"}
+		if !reflect.DeepEqual(exp, got) {
+			t.Errorf("expected %v but got %v", exp, got)
+		}
+	})
+
+	t.Run("Older listings stop without seen listings", func(t *testing.T) {
+		scraped := map[string]cache.CachedListing{
+			URL: {URL: "https://www.ebay.com/itm/15", Date: date.Add(15 * time.Minute)},
+		}
+
+		listing := Listing{ID: "99", URL: "https://www.ebay.com/itm/99", Date: date.Add(-48 * time.Hour)}
+		isNew, b := parseItem(listing, scraped, URL)
+		if isNew || b {
+			t.Errorf("expected the older listing to stop the scan, got isNew=%v and b=%v", isNew, b)
+		}
+	})
+}
+
+func TestItemID(t *testing.T) {
+	tests := map[string]Listing{
+		"402943017690": {URL: "https://www.ebay.com/itm/402943017690?hash=item5dd14682da:g:RdAAAOSwudVg0-jc", ID: "402943017690?hash=item5dd14682da:g:RdAAAOSwudVg0-jc"},
+		"393802831789": {URL: "https://www.ebay.co.uk/itm/Duct-tape-silver/393802831789"},
+		"1234":         {URL: "not a listing URL", ID: "1234?hash=1"},
+	}
+
+	for exp, listing := range tests {
+		if got := listing.ItemID(); got != exp {
+			t.Errorf("expected %s but got %s", exp, got)
+		}
+	}
+}
+
+func TestSourceFor(t *testing.T) {
+	html, rss := &fakeSource{}, &fakeSource{}
+	s := NewScraper(nil, map[string]Source{SourceHTML: html, SourceRSS: rss})
+
+	tests := []struct {
+		source   string
+		expected Source
+	}{
+		{"", html},
+		{SourceHTML, html},
+		{SourceRSS, rss},
+	}
+
+	for _, test := range tests {
+		got, err := s.sourceFor(SearchURL{URL: "https://www.ebay.com/sch/i.html?_nkw=tape", Source: test.source})
+		if err != nil {
+			t.Fatalf("unexpected error for source %q: %v", test.source, err)
+		}
+
+		if got != test.expected {
+			t.Errorf("got the wrong listing source for source %q", test.source)
+		}
+	}
+
+	if _, err := s.sourceFor(SearchURL{URL: "https://www.ebay.com/sch/i.html?_nkw=tape", Source: SourceAPI}); err == nil {
+		t.Errorf("expected an error for a source which is not available")
+	}
+}
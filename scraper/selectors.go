@@ -0,0 +1,168 @@
+package scraper
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+)
+
+// SelectorProfile holds the CSS selectors used to extract listings from an eBay search results page.
+// Each field is an ordered list of alternative selectors: the first selector matching something is used, so that new
+// eBay markups can be handled without dropping the older ones.
+type SelectorProfile struct {
+	// Version identifies the profile in the logs, e.g. 2021-06.
+	Version string
+	// Domains restricts the profile to the given location domains. An empty list means all the domains.
+	Domains []string
+
+	Container []string
+	Item      []string
+	Link      []string
+	Title     []string
+	Subtitle  []string
+	Price     []string
+	Date      []string
+	Image     []string
+	Condition []string
+	Shipping  []string
+	Location  []string
+	// Seller is the seller information, starting with the seller username. eBay only shows it on some sites.
+	Seller []string
+	// TimeLeft is the time left before the end of an auction, and Bids its number of bids.
+	TimeLeft []string
+	Bids     []string
+	// Count is the heading announcing the number of results of the page.
+	Count []string
+	// Boundary are the dividers of the results river which may start the "Results matching fewer words" section.
+	Boundary []string
+	// Sponsored are the labels of the sponsored (promoted) items. They are looked for in the whole item wrapper.
+	Sponsored []string
+	// Next is the link to the next results page.
+	Next []string
+}
+
+// defaultProfiles are the built-in selector profiles, from the most recent eBay markup to the oldest one.
+// They are always tried after the profiles coming from the config.
+var defaultProfiles = []SelectorProfile{
+	{
+		Version:   "2021-06",
+		Container: []string{"div#srp-river-results", "ul.srp-results"},
+		Item:      []string{"div.s-item__info"},
+		Link:      []string{"a.s-item__link", "a[href*='/itm/']"},
+		Title:     []string{".s-item__title"},
+		Subtitle:  []string{".s-item__subtitle"},
+		Price:     []string{".s-item__details .s-item__price", ".s-item__price"},
+		Date:      []string{".s-item__details .s-item__listingDate", ".s-item__listingDate"},
+		Image:     []string{"img.s-item__image-img", ".s-item__image img"},
+		Condition: []string{".s-item__subtitle .SECONDARY_INFO"},
+		Shipping:  []string{".s-item__shipping", ".s-item__logisticsCost"},
+		Location:  []string{".s-item__location", ".s-item__itemLocation"},
+		Seller:    []string{".s-item__seller-info-text", ".s-item__seller-info"},
+		TimeLeft:  []string{".s-item__time-left", ".s-item__timeLeft"},
+		Bids:      []string{".s-item__bids", ".s-item__bidCount"},
+		Count:     []string{".srp-controls__count-heading", "h1.srp-controls__count-heading"},
+		Boundary:  []string{".srp-river-answer"},
+		Sponsored: []string{
+			".s-item__sponsored",
+			".s-item__ad-badge",
+			".s-item__title--tagblock__SPONSORED",
+			".s-item__detail--sponsored",
+		},
+		Next: []string{"a.pagination__next", "nav.pagination a[rel='next']", "a[rel='next']"},
+	},
+}
+
+// DefaultProfile returns the most recent built-in selector profile.
+func DefaultProfile() SelectorProfile {
+	return defaultProfiles[0]
+}
+
+// withDefaults returns a copy of the profile where every empty selector list is taken from the given fallback
+// profile. That way, a profile from the config only needs to override the selectors that changed.
+func (p SelectorProfile) withDefaults(fallback SelectorProfile) SelectorProfile {
+	fill := func(s []string, def []string) []string {
+		if len(s) == 0 {
+			return def
+		}
+		return s
+	}
+
+	p.Container = fill(p.Container, fallback.Container)
+	p.Item = fill(p.Item, fallback.Item)
+	p.Link = fill(p.Link, fallback.Link)
+	p.Title = fill(p.Title, fallback.Title)
+	p.Subtitle = fill(p.Subtitle, fallback.Subtitle)
+	p.Price = fill(p.Price, fallback.Price)
+	p.Date = fill(p.Date, fallback.Date)
+	p.Image = fill(p.Image, fallback.Image)
+	p.Condition = fill(p.Condition, fallback.Condition)
+	p.Shipping = fill(p.Shipping, fallback.Shipping)
+	p.Location = fill(p.Location, fallback.Location)
+	p.Seller = fill(p.Seller, fallback.Seller)
+	p.TimeLeft = fill(p.TimeLeft, fallback.TimeLeft)
+	p.Bids = fill(p.Bids, fallback.Bids)
+	p.Count = fill(p.Count, fallback.Count)
+	p.Boundary = fill(p.Boundary, fallback.Boundary)
+	p.Sponsored = fill(p.Sponsored, fallback.Sponsored)
+	p.Next = fill(p.Next, fallback.Next)
+
+	return p
+}
+
+// appliesTo returns whether the profile can be used for the given location domain.
+func (p SelectorProfile) appliesTo(domain string) bool {
+	if len(p.Domains) == 0 {
+		return true
+	}
+
+	for _, d := range p.Domains {
+		if d == domain {
+			return true
+		}
+	}
+
+	return false
+}
+
+// profilesFor returns the ordered list of selector profiles to try for the given location domain: first the
+// profiles from the config, then the built-in ones.
+func profilesFor(profiles []SelectorProfile, domain string) []SelectorProfile {
+	res := make([]SelectorProfile, 0, len(profiles)+len(defaultProfiles))
+	for _, p := range profiles {
+		if p.appliesTo(domain) {
+			res = append(res, p.withDefaults(DefaultProfile()))
+		}
+	}
+
+	return append(res, defaultProfiles...)
+}
+
+// findFirst returns the elements matching the first selector of the given list that matches something in sel.
+// It returns an empty selection if none of them match.
+func findFirst(sel *goquery.Selection, selectors []string) *goquery.Selection {
+	found := sel.Slice(0, 0)
+	for _, s := range selectors {
+		found = sel.Find(s)
+		if found.Length() > 0 {
+			return found
+		}
+	}
+
+	return found
+}
+
+// textOf returns the trimmed text of the first element matching one of the given selectors.
+func textOf(sel *goquery.Selection, selectors []string) string {
+	return strings.TrimSpace(findFirst(sel, selectors).First().Text())
+}
+
+// attrOf returns the given attribute of the first element matching one of the given selectors, and whether it
+// exists.
+func attrOf(sel *goquery.Selection, selectors []string, attr string) (string, bool) {
+	for _, s := range selectors {
+		if v, ok := sel.Find(s).Attr(attr); ok {
+			return v, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,72 @@
+package scraper
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProfilesFor(t *testing.T) {
+	profiles := []SelectorProfile{
+		{Version: "uk-only", Domains: []string{"co.uk"}, Title: []string{"h3.title"}},
+		{Version: "all"},
+	}
+
+	got := profilesFor(profiles, "co.uk")
+	if len(got) != 3 {
+		t.Fatalf("expected 3 profiles but got %d", len(got))
+	}
+
+	if got[0].Version != "uk-only" || got[1].Version != "all" || got[2].Version != DefaultProfile().Version {
+		t.Errorf("unexpected profiles order: %s, %s, %s", got[0].Version, got[1].Version, got[2].Version)
+	}
+
+	if !reflect.DeepEqual(got[0].Title, []string{"h3.title"}) {
+		t.Errorf("expected the title selector to be overridden but got %v", got[0].Title)
+	}
+
+	if !reflect.DeepEqual(got[0].Price, DefaultProfile().Price) {
+		t.Errorf("expected the price selector to be inherited but got %v", got[0].Price)
+	}
+
+	got = profilesFor(profiles, "fr")
+	if len(got) != 2 || got[0].Version != "all" {
+		t.Errorf("expected the uk-only profile to be skipped for fr, got %d profiles", len(got))
+	}
+}
+
+func TestFindItems(t *testing.T) {
+	html := `<ul class="results-v2"><li class="card"><a class="card__link" href="https://www.ebay.com/itm/1"><span class="card__title">First</span></a></li></ul>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("could not create document: %v", err)
+	}
+
+	t.Run("Built-in profile does not match", func(t *testing.T) {
+		_, _, items := findItems(doc, profilesFor(nil, "com"))
+		if items.Length() != 0 {
+			t.Errorf("expected zero items but got %d", items.Length())
+		}
+	})
+
+	t.Run("Fallback to the matching profile", func(t *testing.T) {
+		profiles := []SelectorProfile{
+			{Version: "broken", Container: []string{"div#nope"}},
+			{Version: "v2", Container: []string{"div#nope", "ul.results-v2"}, Item: []string{"li.card"}, Title: []string{".card__title"}},
+		}
+
+		profile, _, items := findItems(doc, profilesFor(profiles, "com"))
+		if profile.Version != "v2" {
+			t.Errorf("expected profile v2 but got %s", profile.Version)
+		}
+
+		if items.Length() != 1 {
+			t.Fatalf("expected one item but got %d", items.Length())
+		}
+
+		if got := textOf(items.First(), profile.Title); got != "First" {
+			t.Errorf("expected title First but got %s", got)
+		}
+	})
+}
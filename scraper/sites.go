@@ -0,0 +1,150 @@
+package scraper
+
+import (
+	"fmt"
+	"github.com/goodsign/monday"
+	"net/url"
+	"strings"
+)
+
+// Site describes an eBay site, identified in the config by its location domain.
+type Site struct {
+	// Domain is the location domain of the site, e.g. com, co.uk, be-fr.
+	Domain string
+	// Host is the host name of the site, e.g. www.ebay.com.
+	Host string
+	// Locale is the locale in which the site prints the listing dates.
+	Locale monday.Locale
+	// Language is the ISO 639-1 code of the language of the site.
+	Language string
+	// Currency is the ISO 4217 code of the currency of the prices on the site.
+	Currency string
+	// Timezone is the timezone in which the site prints the listing dates.
+	Timezone string
+	// MarketplaceID is the eBay marketplace ID of the site, used by the Browse API.
+	MarketplaceID string
+	// MonthSuffixes are removed from the months printed by the site before they are parsed, e.g. the dot of déc.
+	MonthSuffixes []string
+	// MonthAliases maps the months printed by the site, once their suffix removed, to the ones of the locale.
+	MonthAliases map[string]string
+}
+
+// sites are the eBay sites handled by the watchdog.
+var sites = []Site{
+	{Domain: "at", Host: "www.ebay.at", Locale: monday.LocaleDeDE, Language: "de", Currency: "EUR", Timezone: "Europe/Vienna", MarketplaceID: "EBAY_AT", MonthSuffixes: []string{"."}, MonthAliases: germanMonthAliases},
+	{Domain: "be-fr", Host: "www.befr.ebay.be", Locale: monday.LocaleFrFR, Language: "fr", Currency: "EUR", Timezone: "Europe/Brussels", MarketplaceID: "EBAY_BE", MonthSuffixes: []string{"."}},
+	{Domain: "be-nl", Host: "www.benl.ebay.be", Locale: monday.LocaleNlBE, Language: "nl", Currency: "EUR", Timezone: "Europe/Brussels", MarketplaceID: "EBAY_BE", MonthSuffixes: []string{"."}},
+	{Domain: "ca", Host: "www.ebay.ca", Locale: monday.LocaleEnUS, Language: "en", Currency: "CAD", Timezone: "America/Toronto", MarketplaceID: "EBAY_CA"},
+	{Domain: "ca-fr", Host: "www.cafr.ebay.ca", Locale: monday.LocaleFrCA, Language: "fr", Currency: "CAD", Timezone: "America/Toronto", MarketplaceID: "EBAY_CA", MonthSuffixes: []string{"."}},
+	{Domain: "ch", Host: "www.ebay.ch", Locale: monday.LocaleDeDE, Language: "de", Currency: "CHF", Timezone: "Europe/Zurich", MarketplaceID: "EBAY_CH", MonthSuffixes: []string{"."}, MonthAliases: germanMonthAliases},
+	{Domain: "co.uk", Host: "www.ebay.co.uk", Locale: monday.LocaleEnGB, Language: "en", Currency: "GBP", Timezone: "Europe/London", MarketplaceID: "EBAY_GB"},
+	{Domain: "com", Host: "www.ebay.com", Locale: monday.LocaleEnUS, Language: "en", Currency: "USD", Timezone: "America/Los_Angeles", MarketplaceID: "EBAY_US"},
+	{Domain: "com.au", Host: "www.ebay.com.au", Locale: monday.LocaleEnUS, Language: "en", Currency: "AUD", Timezone: "Australia/Sydney", MarketplaceID: "EBAY_AU"},
+	{Domain: "com.hk", Host: "www.ebay.com.hk", Locale: monday.LocaleZhHK, Language: "zh", Currency: "HKD", Timezone: "Asia/Hong_Kong", MarketplaceID: "EBAY_HK", MonthSuffixes: []string{"月"}},
+	{Domain: "com.my", Host: "www.ebay.com.my", Locale: monday.LocaleEnUS, Language: "en", Currency: "MYR", Timezone: "Asia/Kuala_Lumpur", MarketplaceID: "EBAY_MY"},
+	{Domain: "com.sg", Host: "www.ebay.com.sg", Locale: monday.LocaleEnUS, Language: "en", Currency: "SGD", Timezone: "Asia/Singapore", MarketplaceID: "EBAY_SG"},
+	{Domain: "de", Host: "www.ebay.de", Locale: monday.LocaleDeDE, Language: "de", Currency: "EUR", Timezone: "Europe/Berlin", MarketplaceID: "EBAY_DE", MonthSuffixes: []string{"."}, MonthAliases: germanMonthAliases},
+	{Domain: "es", Host: "www.ebay.es", Locale: monday.LocaleEsES, Language: "es", Currency: "EUR", Timezone: "Europe/Madrid", MarketplaceID: "EBAY_ES"},
+	{Domain: "fr", Host: "www.ebay.fr", Locale: monday.LocaleFrFR, Language: "fr", Currency: "EUR", Timezone: "Europe/Paris", MarketplaceID: "EBAY_FR", MonthSuffixes: []string{"."}},
+	{Domain: "ie", Host: "www.ebay.ie", Locale: monday.LocaleEnGB, Language: "en", Currency: "EUR", Timezone: "Europe/Dublin", MarketplaceID: "EBAY_IE"},
+	{Domain: "in", Host: "www.ebay.in", Locale: monday.LocaleEnGB, Language: "en", Currency: "INR", Timezone: "Asia/Kolkata", MarketplaceID: "EBAY_IN"},
+	{Domain: "it", Host: "www.ebay.it", Locale: monday.LocaleItIT, Language: "it", Currency: "EUR", Timezone: "Europe/Rome", MarketplaceID: "EBAY_IT"},
+	{Domain: "nl", Host: "www.ebay.nl", Locale: monday.LocaleNlNL, Language: "nl", Currency: "EUR", Timezone: "Europe/Amsterdam", MarketplaceID: "EBAY_NL", MonthSuffixes: []string{"."}},
+	{Domain: "ph", Host: "www.ebay.ph", Locale: monday.LocaleEnUS, Language: "en", Currency: "PHP", Timezone: "Asia/Manila", MarketplaceID: "EBAY_PH"},
+	{Domain: "pl", Host: "www.ebay.pl", Locale: monday.LocalePlPL, Language: "pl", Currency: "PLN", Timezone: "Europe/Warsaw", MarketplaceID: "EBAY_PL"},
+}
+
+// germanMonthAliases are the German months printed by eBay which differ from the ones of the locale.
+var germanMonthAliases = map[string]string{
+	"Mrz":  "Mär",
+	"März": "Mär",
+	"Jun":  "Juni",
+	"Jul":  "Juli",
+	"Sept": "Sep",
+}
+
+// LookupSite returns the eBay site with the given location domain.
+func LookupSite(domain string) (Site, bool) {
+	for _, s := range sites {
+		if s.Domain == domain {
+			return s, true
+		}
+	}
+
+	return Site{}, false
+}
+
+// LookupSiteByHost returns the eBay site with the given host name. The hosts without www and the mobile ones are
+// accepted as well, e.g. ebay.co.uk or m.ebay.com.
+func LookupSiteByHost(host string) (Site, bool) {
+	host = strings.ToLower(host)
+	host = strings.TrimPrefix(host, "m.")
+	if !strings.HasPrefix(host, "www.") {
+		host = "www." + host
+	}
+
+	for _, s := range sites {
+		if s.Host == host {
+			return s, true
+		}
+	}
+
+	return Site{}, false
+}
+
+// SiteDomains returns the location domains of all the handled eBay sites.
+func SiteDomains() []string {
+	domains := make([]string, len(sites))
+	for i, s := range sites {
+		domains[i] = s.Domain
+	}
+
+	return domains
+}
+
+// formatMonth returns the given month, as printed by the site, in the form expected by the locale of the site.
+func (s Site) formatMonth(month string) string {
+	for _, suffix := range s.MonthSuffixes {
+		month = strings.TrimSuffix(month, suffix)
+	}
+
+	if alias, ok := s.MonthAliases[month]; ok {
+		return alias
+	}
+
+	return month
+}
+
+// setDomain replaces the host of the given URL by the one of the eBay site with the given location domain, and
+// returns the new URL.
+func setDomain(URL string, domain string) (string, error) {
+	site, ok := LookupSite(domain)
+	if !ok {
+		return "", fmt.Errorf("unknown eBay site for domain %s", domain)
+	}
+
+	u, err := url.Parse(URL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("could not extract domain from URL %s", URL)
+	}
+
+	u.Host = site.Host
+
+	return u.String(), nil
+}
+
+// parseLocDomain returns the location domain from the given listing URL.
+// e.g. com, co.uk, fr, etc.
+func parseLocDomain(URL string) (string, error) {
+	u, err := url.Parse(URL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("could not extract location domain from URL %s", URL)
+	}
+
+	site, ok := LookupSiteByHost(u.Host)
+	if !ok {
+		return "", fmt.Errorf("unknown eBay site for host %s", u.Host)
+	}
+
+	return site.Domain, nil
+}
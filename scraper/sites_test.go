@@ -0,0 +1,142 @@
+package scraper
+
+import (
+	"testing"
+	"time"
+)
+
+// siteDateSamples are listing dates as printed by each eBay site, all meaning Nov 17 04:09.
+var siteDateSamples = map[string][]string{
+	"at":     {"17. Nov. 04:09"},
+	"be-fr":  {"17-nov. 04:09"},
+	"be-nl":  {"17-nov 04:09"},
+	"ca":     {"Nov-17 04:09"},
+	"ca-fr":  {"17-nov. 04:09"},
+	"ch":     {"17. Nov. 04:09"},
+	"co.uk":  {"17-Nov 04:09"},
+	"com":    {"Nov-17 04:09"},
+	"com.au": {"Nov-17 04:09"},
+	"com.hk": {"11月-17 04:09"},
+	"com.my": {"Nov-17 04:09"},
+	"com.sg": {"Nov-17 04:09"},
+	"de":     {"17. Nov. 04:09"},
+	"es":     {"17-nov 04:09"},
+	"fr":     {"nov.-17 04:09"},
+	"ie":     {"17-Nov 04:09"},
+	"in":     {"17-Nov 04:09"},
+	"it":     {"17-nov 04:09"},
+	"nl":     {"17-nov 04:09"},
+	"ph":     {"Nov-17 04:09"},
+	"pl":     {"17-Lis 04:09"},
+}
+
+func TestSites(t *testing.T) {
+	domains := make(map[string]bool)
+	hosts := make(map[string]bool)
+	for _, site := range sites {
+		if domains[site.Domain] || hosts[site.Host] {
+			t.Errorf("site %s is registered twice", site.Domain)
+		}
+		domains[site.Domain] = true
+		hosts[site.Host] = true
+
+		if _, err := site.location(); err != nil {
+			t.Errorf("could not load the timezone of %s: %v", site.Domain, err)
+		}
+
+		if site.Locale == "" || site.Language == "" || site.Currency == "" || site.MarketplaceID == "" {
+			t.Errorf("site %s is incomplete: %+v", site.Domain, site)
+		}
+	}
+
+	if _, ok := LookupSite("xyz"); ok {
+		t.Errorf("expected no site for an unknown domain")
+	}
+
+	if _, err := siteLocation("xyz"); err == nil {
+		t.Errorf("expected an error for an unknown domain")
+	}
+}
+
+func TestSitesDomainRoundTrip(t *testing.T) {
+	searchURL := "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10"
+	for _, site := range sites {
+		URL, err := setDomain(searchURL, site.Domain)
+		if err != nil {
+			t.Errorf("could not set domain %s: %v", site.Domain, err)
+			continue
+		}
+
+		exp := "https://" + site.Host + "/sch/i.html?_nkw=tape&_sop=10"
+		if URL != exp {
+			t.Errorf("expected %s but got %s", exp, URL)
+		}
+
+		got, err := parseLocDomain(URL)
+		if err != nil {
+			t.Errorf("could not parse domain of %s: %v", URL, err)
+			continue
+		}
+
+		if got != site.Domain {
+			t.Errorf("expected %s but got %s", site.Domain, got)
+		}
+	}
+
+	if _, err := setDomain(searchURL, "xyz"); err == nil {
+		t.Errorf("expected an error for an unknown domain")
+	}
+
+	if _, err := parseLocDomain("https://www.example.com/itm/1"); err == nil {
+		t.Errorf("expected an error for an unknown host")
+	}
+}
+
+func TestSitesDates(t *testing.T) {
+	defer pinLocalTimezone()()
+
+	for _, site := range sites {
+		samples, ok := siteDateSamples[site.Domain]
+		if !ok {
+			t.Errorf("missing date samples for site %s", site.Domain)
+			continue
+		}
+
+		exp := time.Date(testNow.Year(), 11, 17, 4, 9, 0, 0, mustSiteLocation(t, site.Domain))
+		for _, sample := range samples {
+			got, err := parseDate(sample, "https://"+site.Host+"/itm/1", testNow)
+			if err != nil {
+				t.Errorf("could not parse date %s of site %s: %v", sample, site.Domain, err)
+				continue
+			}
+
+			if !exp.Equal(got) {
+				t.Errorf("expected %v but got %v for date %s of site %s", exp, got, sample, site.Domain)
+			}
+		}
+	}
+}
+
+func TestSiteFormatMonth(t *testing.T) {
+	tests := []struct {
+		domain string
+		month  string
+		exp    string
+	}{
+		{domain: "fr", month: "déc.", exp: "déc"},
+		{domain: "fr", month: "févr.", exp: "févr"},
+		{domain: "fr", month: "juil.", exp: "juil"},
+		{domain: "de", month: "Nov.", exp: "Nov"},
+		{domain: "de", month: "März", exp: "Mär"},
+		{domain: "com.hk", month: "12月", exp: "12"},
+		{domain: "com", month: "Jun", exp: "Jun"},
+	}
+
+	for _, tt := range tests {
+		site, _ := LookupSite(tt.domain)
+		got := site.formatMonth(tt.month)
+		if got != tt.exp {
+			t.Errorf("expected %s but got %s for month %s of site %s", tt.exp, got, tt.month, tt.domain)
+		}
+	}
+}
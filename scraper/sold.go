@@ -0,0 +1,125 @@
+package scraper
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ScrapeSold returns the recently sold listings of each search, by search URL, from the first page of the sold
+// results on each domain of the search. The sold results are always read from the search results pages.
+func (s *Scraper) ScrapeSold() map[string][]Listing {
+	source, ok := s.Sources[SourceHTML].(SoldSource)
+	if !ok {
+		log.Println("no listing source can fetch the sold listings, skipping")
+		return nil
+	}
+
+	log.Println("Scraping sold listings")
+	res := make(map[string][]Listing)
+	// The sites which blocked a request are not requested again.
+	blocked := make(map[string]bool)
+	for _, searchURL := range s.URLs {
+		domains := searchURL.Domains
+		if len(domains) == 0 {
+			domain, err := parseLocDomain(searchURL.URL)
+			if err != nil {
+				log.Printf("could not get domain from url %s: %s\n", searchURL.URL, err)
+				continue
+			}
+			domains = []string{domain}
+		}
+
+		for _, domain := range domains {
+			URL, err := soldURL(searchURL.URL, domain)
+			if err != nil {
+				log.Printf("could not build the sold search URL of %s: %s\n", searchURL.URL, err)
+				continue
+			}
+
+			if blocked[domain] {
+				log.Printf("Skipping sold url %s, domain %s is blocked\n", URL, domain)
+				continue
+			}
+
+			listings, err := source.FetchSold(URL, domain)
+			if err != nil {
+				log.Printf("could not fetch the sold listings of search URL %s: %s\n", URL, err)
+				if blockedError(err) != nil {
+					blocked[domain] = true
+				}
+			}
+			res[searchURL.URL] = append(res[searchURL.URL], listings...)
+
+			// We space each queries just in case, to prevent getting throttled
+			time.Sleep(s.Pause)
+		}
+	}
+
+	return res
+}
+
+// soldURL returns the given search URL, set to the given location domain, and restricted to the sold listings.
+func soldURL(URL string, domain string) (string, error) {
+	URL, err := setDomain(URL, domain)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(URL)
+	if err != nil {
+		return "", err
+	}
+
+	params := u.Query()
+	params.Set("LH_Sold", "1")
+	params.Set("LH_Complete", "1")
+	u.RawQuery = params.Encode()
+
+	return u.String(), nil
+}
+
+// FetchSold implements SoldSource.
+func (h *HTMLSource) FetchSold(URL string, domain string) ([]Listing, error) {
+	doc, err := h.Client.Get(URL)
+	if err != nil {
+		return nil, fmt.Errorf("could not make request to sold search URL page %s: %w", URL, err)
+	}
+
+	return parseSoldPage(doc, profilesFor(h.Profiles, domain)), nil
+}
+
+// parseSoldPage returns the sold listings of the given search results page, without the sponsored ones and the
+// results matching fewer words. The sold dates are not parsed.
+func parseSoldPage(doc *goquery.Document, profiles []SelectorProfile) []Listing {
+	profile, container, itemInfoList := findItems(doc, profiles)
+	boundary := boundaryIndex(container, itemInfoList, profile)
+
+	var listings []Listing
+	itemInfoList.EachWithBreak(func(i int, sel *goquery.Selection) bool {
+		if boundary >= 0 && i >= boundary {
+			return false
+		}
+
+		rawURL, exists := attrOf(sel, profile.Link, "href")
+		if !exists || len(sel.Children().Nodes) < 3 || isSponsored(sel, profile) {
+			return true
+		}
+
+		URL := strings.Split(rawURL, "&amdata")[0]
+		split := strings.Split(URL, "/")
+		listings = append(listings, Listing{
+			URL:   URL,
+			ID:    split[len(split)-1],
+			Title: textOf(sel, profile.Title),
+			Price: textOf(sel, profile.Price),
+		})
+
+		return true
+	})
+
+	return listings
+}
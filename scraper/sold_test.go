@@ -0,0 +1,79 @@
+package scraper
+
+import (
+	"ebay-watchdog/web"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseSoldPage(t *testing.T) {
+	doc := loadFixture(t, "sold", "com.html")
+	listings := parseSoldPage(doc, profilesFor(nil, "com"))
+
+	expPrices := []string{"$15.00", "$18.50", "$1,020.00"}
+	if len(listings) != len(expPrices) {
+		t.Fatalf("expected %d sold listings before the divider but got %d", len(expPrices), len(listings))
+	}
+
+	for i, l := range listings {
+		if l.Price != expPrices[i] {
+			t.Errorf("expected price %s but got %s", expPrices[i], l.Price)
+		}
+
+		if _, err := l.ParsedPrice(); err != nil {
+			t.Errorf("could not parse price %s: %v", l.Price, err)
+		}
+	}
+
+	if listings[0].ItemID() != "100000000011" {
+		t.Errorf("unexpected item ID %s", listings[0].ItemID())
+	}
+}
+
+func TestSoldURL(t *testing.T) {
+	got, err := soldURL("https://www.ebay.com/sch/i.html?_nkw=duct+tape&_sop=10", "co.uk")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := "https://www.ebay.co.uk/sch/i.html?LH_Complete=1&LH_Sold=1&_nkw=duct+tape&_sop=10"
+	if got != exp {
+		t.Errorf("expected %s but got %s", exp, got)
+	}
+}
+
+func TestScrapeSoldBlockedDomain(t *testing.T) {
+	date := time.Date(2021, 6, 26, 6, 0, 0, 0, time.UTC)
+	tape := "https://www.ebay.com/sch/i.html?_nkw=tape&_sop=10"
+	glue := "https://www.ebay.com/sch/i.html?_nkw=glue&_sop=10"
+	tapeCom, _ := soldURL(tape, "com")
+	tapeDE, _ := soldURL(tape, "de")
+	glueCom, _ := soldURL(glue, "com")
+	glueDE, _ := soldURL(glue, "de")
+
+	source := &fakeSource{
+		pages: map[string]Page{
+			tapeCom: {Listings: fakeListings(2, 1, date)},
+			glueCom: {Listings: fakeListings(4, 3, date)},
+		},
+		errs: map[string]error{
+			tapeDE: fmt.Errorf("could not make request: %w", &web.BlockedError{URL: tapeDE, StatusCode: 429}),
+		},
+	}
+
+	domains := []string{"com", "de"}
+	s := NewScraper([]SearchURL{{URL: tape, Domains: domains}, {URL: glue, Domains: domains}}, map[string]Source{SourceHTML: source})
+	s.Pause = 0
+
+	res := s.ScrapeSold()
+	if len(res[tape]) != 2 || len(res[glue]) != 2 {
+		t.Errorf("expected the sold listings of the other domain, got %+v", res)
+	}
+
+	for _, URL := range source.requested {
+		if URL == glueDE {
+			t.Errorf("expected the blocked domain to be skipped, but %s was requested", URL)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package scraper
+
+// Names of the available listing sources, as used in the config.
+const (
+	SourceHTML = "html"
+	SourceAPI  = "api"
+	SourceRSS  = "rss"
+)
+
+// Source fetches the listings of an eBay search.
+type Source interface {
+	// Fetch returns the listings of the given search, for the given location domain. URL is the search URL, already
+	// set to the location domain. The listings must be sorted from the newest to the oldest, except for the sponsored
+	// ones.
+	Fetch(searchURL SearchURL, URL string, domain string) (Page, error)
+}
+
+// Page is a page of listings returned by a Source.
+type Page struct {
+	Listings []Listing
+	// Next is the URL to give to the same Source in order to fetch the next page of listings. It is empty when there
+	// is no next page, or when the next pages should not be read.
+	Next string
+}
+
+// SoldSource fetches the recently sold listings of an eBay search.
+type SoldSource interface {
+	// FetchSold returns the sold listings of the given sold search URL, for the given location domain. Only their
+	// URL, ID, title and price are required.
+	FetchSold(URL string, domain string) ([]Listing, error)
+}
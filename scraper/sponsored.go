@@ -0,0 +1,43 @@
+package scraper
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+)
+
+// sponsoredWordings are the labels eBay gives to the sponsored items, in lower case. Each site is covered by the
+// wording of its language, e.g. the fr one for be-fr and ca-fr, or the nl one for be-nl.
+var sponsoredWordings = []string{
+	"sponsored",     // en
+	"sponsorisé",    // fr
+	"gesponsert",    // de
+	"patrocinado",   // es
+	"sponsorizzato", // it
+	"gesponsord",    // nl
+	"sponsorowane",  // pl
+	"贊助",            // zh
+}
+
+// isSponsored returns whether the given item is a sponsored (promoted) listing. Sponsored listings are injected in the
+// results river regardless of their publication date.
+func isSponsored(sel *goquery.Selection, profile SelectorProfile) bool {
+	wrapper := sel.Parent()
+	if findFirst(wrapper, profile.Sponsored).Length() > 0 {
+		return true
+	}
+
+	// Some markups only have a plain text label among the item details.
+	found := false
+	wrapper.Find("span").EachWithBreak(func(i int, span *goquery.Selection) bool {
+		text := strings.ToLower(strings.TrimSpace(span.Text()))
+		for _, w := range sponsoredWordings {
+			if text == w {
+				found = true
+				return false
+			}
+		}
+		return true
+	})
+
+	return found
+}
@@ -0,0 +1,161 @@
+package scraper
+
+import (
+	"ebay-watchdog/cache"
+	"ebay-watchdog/web"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sponsoredTestItem(id string, date string, label string) string {
+	return fmt.Sprintf(`<li class="s-item"><div class="s-item__wrapper"><div class="s-item__image-section">%s</div><div class="s-item__info"><a class="s-item__link" href="https://www.ebay.com/itm/%s"><h3 class="s-item__title">Item %s</h3></a><div class="s-item__subtitle"></div><div class="s-item__details"><span class="s-item__price">$1.00</span><span class="s-item__listingDate">%s</span></div></div></div></li>`, label, id, id, date)
+}
+
+func TestIsSponsored(t *testing.T) {
+	tests := map[string]bool{
+		`<span class="s-item__ad-badge"></span>`:             true,
+		`<div><span>Sponsored</span></div>`:                  true,
+		`<div><span> Gesponsert </span></div>`:               true,
+		`<div><span>Sponsored by the community</span></div>`: false,
+		``: false,
+	}
+
+	for label, exp := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(sponsoredTestItem("1", "Jun-26 06:21", label)))
+		if err != nil {
+			t.Fatalf("could not create document: %v", err)
+		}
+
+		got := isSponsored(doc.Find("div.s-item__info"), DefaultProfile())
+		if got != exp {
+			t.Errorf("expected %v but got %v for label %s", exp, got, label)
+		}
+	}
+}
+
+func TestIsSponsoredSites(t *testing.T) {
+	// The plain text label of each site which does not have its own wording.
+	labels := map[string]string{
+		"be-fr":  "Sponsorisé",
+		"be-nl":  "Gesponsord",
+		"ca-fr":  "Sponsorisé",
+		"com.hk": "贊助",
+		"in":     "Sponsored",
+	}
+
+	for domain, label := range labels {
+		html := sponsoredTestItem("1", "Jun-26 06:21", "<div><span>"+label+"</span></div>")
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			t.Fatalf("could not create document: %v", err)
+		}
+
+		if !isSponsored(doc.Find("div.s-item__info"), profilesFor(nil, domain)[0]) {
+			t.Errorf("expected the %s label %s to be detected", domain, label)
+		}
+	}
+}
+
+func TestParseItemSponsored(t *testing.T) {
+	// Scraped in summer, so that the sponsored listing of January is months old.
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	year := now.Year()
+	html := `<ul>` +
+		sponsoredTestItem("3", "Jan-01 00:10", `<span class="s-item__ad-badge"></span>`) +
+		sponsoredTestItem("2", "Jun-26 06:21", "") +
+		sponsoredTestItem("1", "Jun-26 05:00", "") +
+		`</ul>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("could not create document: %v", err)
+	}
+
+	searchURL := "https://www.ebay.com/sch/i.html?_nkw=test&_sop=10"
+	scraped := map[string]cache.CachedListing{
+		searchURL: {
+			URL:  "https://www.ebay.com/itm/1",
+			Date: time.Date(year, 6, 26, 5, 0, 0, 0, mustSiteLocation(t, "com")),
+		},
+	}
+
+	var got []Listing
+	doc.Find("div.s-item__info").EachWithBreak(func(i int, sel *goquery.Selection) bool {
+		listing, err := extractItem(sel, DefaultProfile(), now)
+		if err != nil || listing == nil {
+			t.Fatalf("could not extract item: %v", err)
+		}
+
+		isNew, b := parseItem(*listing, scraped, searchURL)
+		if isNew {
+			got = append(got, *listing)
+		}
+		return b
+	})
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 listings but got %d", len(got))
+	}
+
+	if !got[0].Sponsored || got[0].Title != "Item 3" {
+		t.Errorf("expected the old sponsored listing not to stop the scraping, got %+v", got[0])
+	}
+
+	if got[1].Sponsored || got[1].Title != "Item 2" {
+		t.Errorf("expected the new organic listing, got %+v", got[1])
+	}
+
+	t.Run("Sponsored listings are skipped by default", func(t *testing.T) {
+		if keepListing(got[0], SearchURL{}, scraped, searchURL) {
+			t.Errorf("expected the sponsored listing to be skipped")
+		}
+	})
+
+	t.Run("Old sponsored listings are skipped", func(t *testing.T) {
+		if keepListing(got[0], SearchURL{NotifySponsored: true}, scraped, searchURL) {
+			t.Errorf("expected the old sponsored listing to be skipped")
+		}
+	})
+
+	t.Run("New sponsored listings are kept when asked", func(t *testing.T) {
+		listing := got[0]
+		listing.Date = time.Date(year, 6, 26, 7, 0, 0, 0, mustSiteLocation(t, "com"))
+		if !keepListing(listing, SearchURL{NotifySponsored: true}, scraped, searchURL) {
+			t.Errorf("expected the new sponsored listing to be kept")
+		}
+	})
+}
+
+func TestParsePageSponsoredWithoutDate(t *testing.T) {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	html := `<ul class="srp-results">` +
+		sponsoredTestItem("3", "", `<span class="s-item__ad-badge"></span>`) +
+		sponsoredTestItem("2", "Jun-26 06:21", "") +
+		sponsoredTestItem("1", "Jun-26 05:00", "") +
+		`</ul><a class="pagination__next" href="https://www.ebay.com/sch/i.html?_nkw=test&_sop=10&_pgn=2"></a>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("could not create document: %v", err)
+	}
+
+	source := NewHTMLSource(nil, web.DefaultClient)
+	source.Now = func() time.Time { return now }
+	URL := "https://www.ebay.com/sch/i.html?_nkw=test&_sop=10"
+	page := source.parsePage(doc, SearchURL{URL: URL}, URL, "com")
+
+	if len(page.Listings) != 3 {
+		t.Fatalf("expected the listings after the sponsored one to be kept, got %+v", page.Listings)
+	}
+
+	if !page.Listings[0].Sponsored || !page.Listings[0].Date.IsZero() {
+		t.Errorf("expected the sponsored listing without date, got %+v", page.Listings[0])
+	}
+
+	if page.Next == "" {
+		t.Errorf("expected the next page to be kept")
+	}
+}
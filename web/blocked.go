@@ -0,0 +1,68 @@
+package web
+
+import (
+	"net/url"
+	"sync"
+)
+
+// blockedHosts remembers the hosts which blocked a request, so that the following requests to them fail at once
+// instead of prolonging the block.
+type blockedHosts struct {
+	mu   sync.Mutex
+	errs map[string]*BlockedError
+}
+
+func (b *blockedHosts) add(URL string, err *BlockedError) {
+	host := urlHost(URL)
+	if host == "" {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.errs == nil {
+		b.errs = make(map[string]*BlockedError)
+	}
+	b.errs[host] = err
+}
+
+func (b *blockedHosts) get(URL string) *BlockedError {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.errs[urlHost(URL)]
+}
+
+func (b *blockedHosts) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.errs = nil
+}
+
+// Blocked returns the error of the request which was blocked by the host of the given URL, or nil if the host did
+// not block any request since the last Unblock.
+func (c *Client) Blocked(URL string) *BlockedError {
+	return c.blocked.get(URL)
+}
+
+// Block records that the host of the given URL blocked a request, e.g. a Browse API request of the same eBay site,
+// so that the following requests to it fail at once.
+func (c *Client) Block(URL string, err *BlockedError) {
+	c.blocked.add(URL, err)
+}
+
+// Unblock forgets the blocked hosts, so that they are requested again.
+func (c *Client) Unblock() {
+	c.blocked.reset()
+}
+
+// urlHost returns the host of the given URL, or an empty string if it cannot be parsed.
+func urlHost(URL string) string {
+	u, err := url.Parse(URL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Host
+}
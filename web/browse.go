@@ -0,0 +1,204 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBrowseAPIURL is the base URL of the eBay production APIs.
+const DefaultBrowseAPIURL = "https://api.ebay.com"
+
+const browseAPIScope = "https://api.ebay.com/oauth/api_scope"
+
+// BrowseClient queries the eBay Browse API, using an application access token obtained with the OAuth client
+// credentials grant.
+type BrowseClient struct {
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	HTTPClient   *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// ItemSummary is an item returned by the Browse API item_summary/search endpoint.
+// Only the fields used by the watchdog are mapped.
+type ItemSummary struct {
+	ItemID           string  `json:"itemId"`
+	Title            string  `json:"title"`
+	ShortDescription string  `json:"shortDescription"`
+	ItemWebURL       string  `json:"itemWebUrl"`
+	Price            *Amount `json:"price"`
+	CurrentBidPrice  *Amount `json:"currentBidPrice"`
+	Image            *struct {
+		ImageURL string `json:"imageUrl"`
+	} `json:"image"`
+	Condition        string   `json:"condition"`
+	ItemCreationDate string   `json:"itemCreationDate"`
+	ItemEndDate      string   `json:"itemEndDate"`
+	BuyingOptions    []string `json:"buyingOptions"`
+	BidCount         int      `json:"bidCount"`
+	PriorityListing  bool     `json:"priorityListing"`
+	Seller           *struct {
+		Username           string `json:"username"`
+		FeedbackScore      int    `json:"feedbackScore"`
+		FeedbackPercentage string `json:"feedbackPercentage"`
+	} `json:"seller"`
+	ItemLocation *struct {
+		City     string `json:"city"`
+		Country  string `json:"country"`
+		PostCode string `json:"postalCode"`
+	} `json:"itemLocation"`
+	ShippingOptions []struct {
+		ShippingCost *Amount `json:"shippingCost"`
+	} `json:"shippingOptions"`
+}
+
+// Amount is a monetary amount returned by the Browse API.
+type Amount struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+// SearchResponse is the response of the Browse API item_summary/search endpoint.
+type SearchResponse struct {
+	Total         int           `json:"total"`
+	Next          string        `json:"next"`
+	ItemSummaries []ItemSummary `json:"itemSummaries"`
+}
+
+func NewBrowseClient(baseURL string, clientID string, clientSecret string) *BrowseClient {
+	if baseURL == "" {
+		baseURL = DefaultBrowseAPIURL
+	}
+
+	return &BrowseClient{
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		HTTPClient:   DefaultClient.API,
+	}
+}
+
+// Search queries the item_summary/search endpoint with the given query parameters, on the given marketplace, e.g.
+// EBAY_US.
+func (b *BrowseClient) Search(params url.Values, marketplaceID string) (*SearchResponse, error) {
+	return b.SearchURL(fmt.Sprintf("%s/buy/browse/v1/item_summary/search?%s", b.BaseURL, params.Encode()), marketplaceID)
+}
+
+// SearchURL queries the given item_summary/search URL, e.g. the next page URL of a previous response.
+func (b *BrowseClient) SearchURL(URL string, marketplaceID string) (*SearchResponse, error) {
+	token, err := b.accessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Authorization", "Bearer "+token)
+	req.Header.Add("X-EBAY-C-MARKETPLACE-ID", marketplaceID)
+	req.Header.Add("Accept", "application/json")
+
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return nil, requestError(URL, fmt.Errorf("could not make Browse API request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// The token may have been revoked: get a new one next time.
+		b.mu.Lock()
+		b.token = ""
+		b.mu.Unlock()
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, browseResponseError(URL, resp)
+	}
+
+	var res SearchResponse
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return nil, &ParseError{URL: URL, Err: err}
+	}
+
+	return &res, nil
+}
+
+// accessToken returns a valid application access token, requesting a new one when needed.
+func (b *BrowseClient) accessToken() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.token != "" && time.Now().Before(b.tokenExpiry) {
+		return b.token, nil
+	}
+
+	if b.ClientID == "" || b.ClientSecret == "" {
+		return "", fmt.Errorf("missing eBay API client ID or client secret")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("scope", browseAPIScope)
+
+	req, err := http.NewRequest("POST", b.BaseURL+"/identity/v1/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.SetBasicAuth(b.ClientID, b.ClientSecret)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return "", requestError(req.URL.String(), fmt.Errorf("could not make OAuth token request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not get an OAuth token: %w", browseResponseError(req.URL.String(), resp))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("could not decode OAuth token response: %v", err)
+	}
+
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("OAuth token response has no access token")
+	}
+
+	b.token = token.AccessToken
+	// Renew the token a bit before it actually expires.
+	b.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+
+	return b.token, nil
+}
+
+// browseResponseError returns the error of the given unsuccessful Browse API response: a BlockedError when the
+// application is rate limited, and a StatusError otherwise. A forbidden status code is a StatusError, as it comes from
+// a missing scope or an invalid application rather than from the request rate.
+func browseResponseError(URL string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &BlockedError{URL: URL, StatusCode: resp.StatusCode, Reason: "the API rate limit was reached"}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4*maxErrorBody))
+	return &StatusError{URL: URL, StatusCode: resp.StatusCode, Body: snippet(body)}
+}
@@ -0,0 +1,156 @@
+package web
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"crypto/tls"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ClientOptions configure a Client. The zero values use the defaults.
+type ClientOptions struct {
+	// PageTimeout, APITimeout and TelegramTimeout are the timeouts of the requests to the eBay pages, the eBay APIs
+	// and the Telegram bot API. Default to 10 seconds.
+	PageTimeout     time.Duration
+	APITimeout      time.Duration
+	TelegramTimeout time.Duration
+	// MaxIdleConns is the maximum number of idle connections kept for reuse, MaxIdleConnsPerHost the maximum for each
+	// host. Default to 100 and 10.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is the period after which an idle connection is closed. Defaults to 90 seconds.
+	IdleConnTimeout time.Duration
+	// DisableHTTP2 only uses HTTP/1.1.
+	DisableHTTP2 bool
+}
+
+// Client is the HTTP client shared by the scraper and the notifiers. Its HTTP clients have their own timeouts, but
+// share the same connections, which are kept alive between the requests, and decode the compressed responses.
+type Client struct {
+	// Pages requests the eBay pages: search results, item pages, RSS feeds and images.
+	Pages *http.Client
+	// API requests the eBay APIs.
+	API *http.Client
+	// Telegram requests the Telegram bot API.
+	Telegram *http.Client
+
+	transport *http.Transport
+	blocked   blockedHosts
+}
+
+// DefaultClient is the client used by the components which are not given one.
+var DefaultClient = NewClient(ClientOptions{})
+
+func NewClient(opts ClientOptions) *Client {
+	withDefault := func(d time.Duration, def time.Duration) time.Duration {
+		if d <= 0 {
+			return def
+		}
+		return d
+	}
+
+	maxIdle := opts.MaxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = 100
+	}
+
+	maxIdlePerHost := opts.MaxIdleConnsPerHost
+	if maxIdlePerHost <= 0 {
+		maxIdlePerHost = 10
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     !opts.DisableHTTP2,
+		MaxIdleConns:          maxIdle,
+		MaxIdleConnsPerHost:   maxIdlePerHost,
+		IdleConnTimeout:       withDefault(opts.IdleConnTimeout, 90*time.Second),
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		// The responses are decoded by decodingTransport, which also accepts brotli.
+		DisableCompression: true,
+	}
+	if opts.DisableHTTP2 {
+		// A non-nil empty map disables HTTP/2.
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	rt := &decodingTransport{next: transport}
+
+	return &Client{
+		Pages:     &http.Client{Transport: rt, Timeout: withDefault(opts.PageTimeout, 10*time.Second)},
+		API:       &http.Client{Transport: rt, Timeout: withDefault(opts.APITimeout, 10*time.Second)},
+		Telegram:  &http.Client{Transport: rt, Timeout: withDefault(opts.TelegramTimeout, 10*time.Second)},
+		transport: transport,
+	}
+}
+
+// decodingTransport asks for compressed responses, and decodes them: gzip, deflate and brotli.
+type decodingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *decodingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") != "" || req.Method == "HEAD" {
+		return t.next.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded io.Reader
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "":
+		return resp, nil
+	case "gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("could not decode gzip response: %v", err)
+		}
+		decoded = gz
+	case "deflate":
+		decoded = flate.NewReader(resp.Body)
+	case "br":
+		decoded = brotli.NewReader(resp.Body)
+	default:
+		return resp, nil
+	}
+
+	resp.Body = &decodedBody{Reader: decoded, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
+
+// decodedBody reads a decoded response body, and closes the original one.
+type decodedBody struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+func (d *decodedBody) Close() error {
+	if c, ok := d.Reader.(io.Closer); ok {
+		c.Close()
+	}
+
+	return d.body.Close()
+}
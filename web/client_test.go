@@ -0,0 +1,219 @@
+package web
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/tls"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const testPage = `<html><body><ul class="srp-results"><li class="s-item">Nintendo Switch</li></ul></body></html>`
+
+// encode returns the given body compressed with the given encoding.
+func encode(t testing.TB, encoding string, body string) []byte {
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(buf)
+	case "deflate":
+		w, _ = flate.NewWriter(buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(buf)
+	default:
+		return []byte(body)
+	}
+
+	if _, err := io.WriteString(w, body); err != nil {
+		t.Fatalf("could not encode body: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("could not encode body: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// compressingHandler serves the test page, compressed with the first encoding accepted by the request among the
+// given ones.
+func compressingHandler(t testing.TB, encodings ...string) http.HandlerFunc {
+	bodies := make(map[string][]byte)
+	for _, e := range encodings {
+		bodies[e] = encode(t, e, strings.Repeat(testPage, 20))
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		accepted := r.Header.Get("Accept-Encoding")
+		for _, e := range encodings {
+			if strings.Contains(accepted, e) {
+				w.Header().Set("Content-Encoding", e)
+				w.Write(bodies[e])
+				return
+			}
+		}
+
+		io.WriteString(w, strings.Repeat(testPage, 20))
+	}
+}
+
+// testTLSConfig returns a TLS config trusting the certificate of the given test server.
+func testTLSConfig(server *httptest.Server) *tls.Config {
+	return &tls.Config{RootCAs: server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+}
+
+func TestClientDecoding(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate", "br", "identity"} {
+		t.Run(encoding, func(t *testing.T) {
+			server := httptest.NewServer(compressingHandler(t, encoding))
+			defer server.Close()
+
+			body, err := NewClient(ClientOptions{}).GetBody(server.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(body) != strings.Repeat(testPage, 20) {
+				t.Errorf("got body %q", body)
+			}
+		})
+	}
+
+	t.Run("Document", func(t *testing.T) {
+		server := httptest.NewServer(compressingHandler(t, "br"))
+		defer server.Close()
+
+		doc, err := NewClient(ClientOptions{}).Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := doc.Find("li.s-item").First().Text(); got != "Nintendo Switch" {
+			t.Errorf("got item %q, expected Nintendo Switch", got)
+		}
+	})
+}
+
+func TestClientReusesConnections(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(compressingHandler(t, "gzip"))
+	server.EnableHTTP2 = true
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	client := NewClient(ClientOptions{})
+	client.transport.TLSClientConfig = testTLSConfig(server)
+
+	for i := 0; i < 10; i++ {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/sch/i.html?_nkw=switch&_pgn=%d", server.URL, i+1), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		resp, err := client.Pages.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.ProtoMajor != 2 {
+			t.Errorf("got protocol %s, expected HTTP/2", resp.Proto)
+		}
+	}
+
+	if got := atomic.LoadInt32(&conns); got != 1 {
+		t.Errorf("got %d connections, expected 1", got)
+	}
+
+	t.Run("HTTP/1.1", func(t *testing.T) {
+		client := NewClient(ClientOptions{DisableHTTP2: true})
+		client.transport.TLSClientConfig = testTLSConfig(server)
+
+		resp, err := client.Pages.Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.ProtoMajor != 1 {
+			t.Errorf("got protocol %s, expected HTTP/1.1", resp.Proto)
+		}
+	})
+}
+
+// BenchmarkCycle fetches the pages of a scraping cycle with many search URLs over many eBay domains, each domain
+// being a different TLS server, either with a client shared by the whole cycle, or with a new connection per request
+// as without connection reuse. It reports the connections opened by cycle.
+func BenchmarkCycle(b *testing.B) {
+	const domains = 10
+	const searchURLs = 20
+
+	var conns int32
+	servers := make([]*httptest.Server, domains)
+	for i := range servers {
+		server := httptest.NewUnstartedServer(compressingHandler(b, "br", "gzip"))
+		server.EnableHTTP2 = true
+		server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt32(&conns, 1)
+			}
+		}
+		server.StartTLS()
+		defer server.Close()
+		servers[i] = server
+	}
+	tlsConfig := testTLSConfig(servers[0])
+
+	cycle := func(b *testing.B, client func() *Client) {
+		for _, server := range servers {
+			for j := 0; j < searchURLs; j++ {
+				if _, err := client().GetBody(fmt.Sprintf("%s/sch/i.html?_nkw=search%d", server.URL, j)); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		}
+	}
+
+	b.Run("Shared", func(b *testing.B) {
+		shared := NewClient(ClientOptions{})
+		shared.transport.TLSClientConfig = tlsConfig
+		atomic.StoreInt32(&conns, 0)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			cycle(b, func() *Client { return shared })
+		}
+
+		b.ReportMetric(float64(atomic.LoadInt32(&conns))/float64(b.N), "conns/op")
+	})
+
+	b.Run("PerRequest", func(b *testing.B) {
+		atomic.StoreInt32(&conns, 0)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			cycle(b, func() *Client {
+				c := NewClient(ClientOptions{})
+				c.transport.TLSClientConfig = tlsConfig.Clone()
+				c.transport.DisableKeepAlives = true
+				return c
+			})
+		}
+
+		b.ReportMetric(float64(atomic.LoadInt32(&conns))/float64(b.N), "conns/op")
+	})
+}
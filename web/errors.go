@@ -0,0 +1,71 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// StatusError is returned when a page responds with a status code other than 200, and the request was not blocked.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	// Body is the beginning of the response body.
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s responded with status code %d: %s", e.URL, e.StatusCode, e.Body)
+}
+
+// TimeoutError is returned when a request, or the reading of its response, timed out.
+type TimeoutError struct {
+	URL string
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("request to %s timed out: %v", e.URL, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// BlockedError is returned when eBay blocked a request, with a captcha page or a forbidden or too many requests status
+// code. The following requests to the same site are likely to be blocked too.
+type BlockedError struct {
+	URL        string
+	StatusCode int
+	// Reason tells how the block was detected.
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("request to %s was blocked: %s", e.URL, e.Reason)
+}
+
+// ParseError is returned when a response could not be parsed.
+type ParseError struct {
+	URL string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("could not parse the response of %s: %v", e.URL, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// requestError returns the given error of a request to the given URL as a TimeoutError when it is a timeout, and
+// unchanged otherwise.
+func requestError(URL string, err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &TimeoutError{URL: URL, Err: err}
+	}
+
+	return err
+}
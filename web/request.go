@@ -1,12 +1,45 @@
 package web
 
 import (
+	"bytes"
+	"fmt"
 	"github.com/PuerkitoBio/goquery"
+	"io"
 	"net/http"
-	"time"
+	"strings"
+	"unicode/utf8"
 )
 
-func Get(URL string) (*goquery.Document, error) {
+// maxErrorBody is the maximum length of the response body kept in a StatusError.
+const maxErrorBody = 200
+
+// captchaMarkers are found in the URL or the body of the pages eBay responds with when it blocks the requests.
+var captchaMarkers = []string{"/splashui/captcha", "Pardon Our Interruption", "px-captcha"}
+
+// Get requests the given eBay page, and returns it parsed. The errors are the ones of GetBody, or a ParseError.
+func (c *Client) Get(URL string) (*goquery.Document, error) {
+	body, err := c.GetBody(URL)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, &ParseError{URL: URL, Err: err}
+	}
+
+	return doc, nil
+}
+
+// GetBody requests the given URL with a desktop User Agent, and returns the raw response body. It returns a
+// BlockedError when eBay blocked the request, a StatusError when the status code is not 200, and a TimeoutError
+// when the request timed out. Once a host blocked a request, the following requests to it are not made until
+// Unblock, and return its BlockedError.
+func (c *Client) GetBody(URL string) ([]byte, error) {
+	if blockedErr := c.Blocked(URL); blockedErr != nil {
+		return nil, &BlockedError{URL: URL, StatusCode: blockedErr.StatusCode, Reason: "host blocked a previous request: " + blockedErr.Reason}
+	}
+
 	req, err := http.NewRequest("GET", URL, nil)
 	if err != nil {
 		return nil, err
@@ -14,25 +47,67 @@ func Get(URL string) (*goquery.Document, error) {
 	// eBay gives a page that's formatted differently if we don't use a desktop User Agent
 	req.Header.Add("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_3) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/80.0.3987.149 Safari/537.36")
 
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+	resp, err := c.Pages.Do(req)
+	if err != nil {
+		return nil, requestError(URL, err)
 	}
 
-	resp, err := client.Do(req)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, requestError(URL, fmt.Errorf("could not read the response: %w", err))
 	}
 
-	defer resp.Body.Close()
+	if reason := blockedReason(resp, body); reason != "" {
+		blockedErr := &BlockedError{URL: URL, StatusCode: resp.StatusCode, Reason: reason}
+		c.blocked.add(URL, blockedErr)
+		return nil, blockedErr
+	}
 
-	if resp.StatusCode != 200 {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: URL, StatusCode: resp.StatusCode, Body: snippet(body)}
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return nil, err
+	return body, nil
+}
+
+// blockedReason returns why the given response shows that eBay blocked the request, or an empty string if it did not.
+func blockedReason(resp *http.Response, body []byte) string {
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		return fmt.Sprintf("status code %d", resp.StatusCode)
 	}
 
-	return doc, nil
+	// The captcha page is usually reached with a redirection.
+	if strings.Contains(resp.Request.URL.Path, "/splashui/captcha") {
+		return "redirected to the captcha page"
+	}
+
+	if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return ""
+	}
+
+	for _, marker := range captchaMarkers {
+		if bytes.Contains(body, []byte(marker)) {
+			return "captcha page"
+		}
+	}
+
+	return ""
+}
+
+// snippet returns the beginning of the given response body, on a single line.
+func snippet(body []byte) string {
+	s := strings.Join(strings.Fields(string(body)), " ")
+	if len(s) <= maxErrorBody {
+		return s
+	}
+
+	s = s[:maxErrorBody]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+
+	return s + "..."
 }
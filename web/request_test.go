@@ -0,0 +1,144 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetBodyErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	var requests int32
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		io.WriteString(w, testPage)
+	})
+	mux.HandleFunc("/unavailable", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "<html>\n  <body>Service   Unavailable</body>\n</html>"+strings.Repeat("x", 300))
+	})
+	mux.HandleFunc("/forbidden", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	mux.HandleFunc("/captcha", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<html><title>Pardon Our Interruption...</title></html>")
+	})
+	mux.HandleFunc("/redirected", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/splashui/captcha?ap=1&appName=orch", http.StatusFound)
+	})
+	mux.HandleFunc("/splashui/captcha", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html></html>")
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, testPage)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient(ClientOptions{PageTimeout: 50 * time.Millisecond})
+
+	t.Run("OK", func(t *testing.T) {
+		body, err := client.GetBody(server.URL + "/ok")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if string(body) != testPage {
+			t.Errorf("got body %q", body)
+		}
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		_, err := client.GetBody(server.URL + "/unavailable")
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("expected a StatusError, got %v", err)
+		}
+
+		if statusErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("got status code %d, expected 503", statusErr.StatusCode)
+		}
+
+		if !strings.HasPrefix(statusErr.Body, "<html> <body>Service Unavailable</body> </html>xxx") || len(statusErr.Body) != maxErrorBody+3 {
+			t.Errorf("got body snippet %q", statusErr.Body)
+		}
+	})
+
+	blocked := map[string]string{
+		"/forbidden":  "status code 403",
+		"/captcha":    "captcha page",
+		"/redirected": "redirected to the captcha page",
+	}
+	for path, reason := range blocked {
+		t.Run("Blocked "+path, func(t *testing.T) {
+			_, err := client.GetBody(server.URL + path)
+
+			var blockedErr *BlockedError
+			if !errors.As(err, &blockedErr) {
+				t.Fatalf("expected a BlockedError, got %v", err)
+			}
+
+			if blockedErr.Reason != reason {
+				t.Errorf("got reason %q, expected %q", blockedErr.Reason, reason)
+			}
+			client.Unblock()
+		})
+	}
+
+	t.Run("Blocked host", func(t *testing.T) {
+		defer client.Unblock()
+		okRequests := atomic.LoadInt32(&requests)
+		if _, err := client.GetBody(server.URL + "/forbidden"); !errors.As(err, new(*BlockedError)) {
+			t.Fatalf("expected a BlockedError, got %v", err)
+		}
+
+		if client.Blocked(server.URL+"/ok") == nil {
+			t.Errorf("expected the host to be blocked")
+		}
+
+		if _, err := client.GetBody(server.URL + "/ok"); !errors.As(err, new(*BlockedError)) {
+			t.Errorf("expected the requests to the blocked host to fail, got %v", err)
+		}
+
+		if atomic.LoadInt32(&requests) != okRequests {
+			t.Errorf("expected no request to be made to the blocked host")
+		}
+
+		client.Unblock()
+		if _, err := client.GetBody(server.URL + "/ok"); err != nil {
+			t.Errorf("unexpected error once unblocked: %v", err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		_, err := client.GetBody(server.URL + "/slow")
+
+		var timeoutErr *TimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("expected a TimeoutError, got %v", err)
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		if _, err := client.Get(server.URL + "/unavailable"); !errors.As(err, new(*StatusError)) {
+			t.Errorf("expected a StatusError, got %v", err)
+		}
+
+		doc, err := client.Get(server.URL + "/ok")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if doc.Find("li.s-item").Length() != 1 {
+			t.Errorf("expected the page to be parsed")
+		}
+	})
+}
@@ -2,13 +2,33 @@ package web
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 )
 
-// SendTelegramMessage sends the given message to the Telegram bot which is bound to the given token and chatID.
-func SendTelegramMessage(token string, chatID string, message string) error {
-	data := fmt.Sprintf("{\"chat_id\": \"%s\", \"text\": \"%s\"}", chatID, message)
+// TelegramButton is an inline button of a Telegram message. Its data is sent back to the bot when it is pressed.
+type TelegramButton struct {
+	Text string `json:"text"`
+	Data string `json:"callback_data"`
+}
+
+// SendTelegramMessage sends the given message to the Telegram bot which is bound to the given token and chatID. A
+// silent message is received without sound. The given buttons, if any, are shown below the message.
+func (c *Client) SendTelegramMessage(token string, chatID string, message string, silent bool, buttons ...TelegramButton) error {
+	markup := ""
+	if len(buttons) > 0 {
+		keyboard, err := json.Marshal(map[string][][]TelegramButton{"inline_keyboard": {buttons}})
+		if err != nil {
+			return fmt.Errorf("could not encode Telegram buttons: %v", err)
+		}
+		markup = fmt.Sprintf(", \"reply_markup\": %s", keyboard)
+	}
+
+	data := fmt.Sprintf("{\"chat_id\": \"%s\", \"text\": \"%s\", \"disable_notification\": %t%s}", chatID, message, silent, markup)
 	req, err := http.NewRequest("GET", fmt.Sprintf("https://api.telegram.org/bot%v/sendMessage", token), bytes.NewBufferString(data))
 	if err != nil {
 		return err
@@ -16,8 +36,7 @@ func SendTelegramMessage(token string, chatID string, message string) error {
 
 	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.Telegram.Do(req)
 	if err != nil {
 		return fmt.Errorf("could not make Telegram request: %v", err)
 	}
@@ -33,3 +52,91 @@ func SendTelegramMessage(token string, chatID string, message string) error {
 
 	return nil
 }
+
+// TelegramCallback is a press on an inline button of a message sent by the bot.
+type TelegramCallback struct {
+	ID     string
+	ChatID string
+	Data   string
+}
+
+type telegramUpdates struct {
+	OK     bool `json:"ok"`
+	Result []struct {
+		UpdateID      int `json:"update_id"`
+		CallbackQuery *struct {
+			ID      string `json:"id"`
+			Data    string `json:"data"`
+			Message struct {
+				Chat struct {
+					ID int64 `json:"id"`
+				} `json:"chat"`
+			} `json:"message"`
+		} `json:"callback_query"`
+	} `json:"result"`
+}
+
+// GetTelegramCallbacks returns the button presses received by the Telegram bot bound to the given token, from the
+// given update offset, waiting up to the given timeout for new ones. It also returns the offset of the next call.
+func (c *Client) GetTelegramCallbacks(token string, offset int, timeout time.Duration) ([]TelegramCallback, int, error) {
+	params := url.Values{}
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("timeout", strconv.Itoa(int(timeout.Seconds())))
+	params.Set("allowed_updates", `["callback_query"]`)
+
+	// The request waits for the updates, on top of the usual timeout
+	client := &http.Client{Transport: c.Telegram.Transport, Timeout: c.Telegram.Timeout + timeout}
+	resp, err := client.Get(fmt.Sprintf("https://api.telegram.org/bot%v/getUpdates?%s", token, params.Encode()))
+	if err != nil {
+		return nil, offset, fmt.Errorf("could not make Telegram request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, offset, fmt.Errorf("telegram responded with status code %v", resp.StatusCode)
+	}
+
+	var updates telegramUpdates
+	err = json.NewDecoder(resp.Body).Decode(&updates)
+	if err != nil {
+		return nil, offset, fmt.Errorf("could not decode Telegram updates: %v", err)
+	}
+
+	var callbacks []TelegramCallback
+	for _, u := range updates.Result {
+		if u.UpdateID >= offset {
+			offset = u.UpdateID + 1
+		}
+
+		if u.CallbackQuery == nil {
+			continue
+		}
+
+		callbacks = append(callbacks, TelegramCallback{
+			ID:     u.CallbackQuery.ID,
+			ChatID: strconv.FormatInt(u.CallbackQuery.Message.Chat.ID, 10),
+			Data:   u.CallbackQuery.Data,
+		})
+	}
+
+	return callbacks, offset, nil
+}
+
+// AnswerTelegramCallback acknowledges the given button press, showing the given text to the user.
+func (c *Client) AnswerTelegramCallback(token string, callbackID string, text string) error {
+	params := url.Values{}
+	params.Set("callback_query_id", callbackID)
+	params.Set("text", text)
+
+	resp, err := c.Telegram.PostForm(fmt.Sprintf("https://api.telegram.org/bot%v/answerCallbackQuery", token), params)
+	if err != nil {
+		return fmt.Errorf("could not make Telegram request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("telegram responded with status code %v", resp.StatusCode)
+	}
+
+	return nil
+}